@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"expvar"
+	"sync"
+)
+
+// Expvar is a pgxpool.MetricsSink publishing the metrics as an expvar.Map.
+// Histograms are published as their _count and _sum.
+type Expvar struct {
+	mu   sync.Mutex
+	vars *expvar.Map
+}
+
+// NewExpvar creates an Expvar sink published under the given name, such as "pgsql".
+// Like expvar.Publish, it panics if the name is already in use.
+func NewExpvar(name string) *Expvar {
+	return &Expvar{vars: expvar.NewMap(name)}
+}
+
+// SetGauge implements pgxpool.MetricsSink.
+func (e *Expvar) SetGauge(name string, labels map[string]string, value float64) {
+	e.float(seriesKey(name, labels)).Set(value)
+}
+
+// AddCounter implements pgxpool.MetricsSink.
+func (e *Expvar) AddCounter(name string, labels map[string]string, delta float64) {
+	e.float(seriesKey(name, labels)).Add(delta)
+}
+
+// ObserveHistogram implements pgxpool.MetricsSink.
+func (e *Expvar) ObserveHistogram(name string, labels map[string]string, value float64) {
+	e.float(seriesKey(name+"_count", labels)).Add(1)
+	e.float(seriesKey(name+"_sum", labels)).Add(value)
+}
+
+// float returns the variable with the given key, creating it if it does not exist yet.
+func (e *Expvar) float(key string) *expvar.Float {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if v, ok := e.vars.Get(key).(*expvar.Float); ok {
+		return v
+	}
+
+	v := new(expvar.Float)
+	e.vars.Set(key, v)
+	return v
+}
@@ -0,0 +1,47 @@
+// Package metrics provides sinks that export the metrics of pgxpool.StatsCollector
+// and pgx.WithMetrics through expvar or in the Prometheus text format.
+package metrics
+
+import (
+	"sort"
+	"strings"
+)
+
+// seriesKey returns the key identifying the series of the metric with the given labels,
+// such as pgsql_pool_idle_conns{node="0",pool="default"}. Labels are sorted by name.
+func seriesKey(name string, labels map[string]string) string {
+	if len(labels) == 0 {
+		return name
+	}
+
+	return name + "{" + formatLabels(labels) + "}"
+}
+
+// formatLabels formats the labels sorted by name, such as node="0",pool="default".
+func formatLabels(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		writeLabel(&b, name, labels[name])
+	}
+
+	return b.String()
+}
+
+// labelEscaper escapes a label value for the Prometheus text format.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func writeLabel(b *strings.Builder, name, value string) {
+	if b.Len() > 0 {
+		b.WriteByte(',')
+	}
+	b.WriteString(name)
+	b.WriteString(`="`)
+	b.WriteString(labelEscaper.Replace(value))
+	b.WriteByte('"')
+}
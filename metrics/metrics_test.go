@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrometheus(t *testing.T) {
+	t.Parallel()
+
+	p := NewPrometheus(0.1, 1)
+	p.SetGauge("pgsql_pool_idle_conns", map[string]string{"pool": "default", "node": "0"}, 2)
+	p.AddCounter("pgsql_tx_begun_total", map[string]string{"pool": `a"b`}, 1)
+	p.AddCounter("pgsql_tx_begun_total", map[string]string{"pool": `a"b`}, 2)
+	p.ObserveHistogram("pgsql_tx_duration_seconds", map[string]string{"pool": "default"}, 0.05)
+	p.ObserveHistogram("pgsql_tx_duration_seconds", map[string]string{"pool": "default"}, 0.5)
+	p.ObserveHistogram("pgsql_tx_duration_seconds", map[string]string{"pool": "default"}, 2)
+
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `# TYPE pgsql_pool_idle_conns gauge
+pgsql_pool_idle_conns{node="0",pool="default"} 2
+# TYPE pgsql_tx_begun_total counter
+pgsql_tx_begun_total{pool="a\"b"} 3
+# TYPE pgsql_tx_duration_seconds histogram
+pgsql_tx_duration_seconds_bucket{pool="default",le="0.1"} 1
+pgsql_tx_duration_seconds_bucket{pool="default",le="1"} 2
+pgsql_tx_duration_seconds_bucket{pool="default",le="+Inf"} 3
+pgsql_tx_duration_seconds_sum{pool="default"} 2.55
+pgsql_tx_duration_seconds_count{pool="default"} 3
+`, rec.Body.String())
+}
+
+func TestExpvar(t *testing.T) {
+	t.Parallel()
+
+	e := NewExpvar("pgsql_test")
+	e.AddCounter("pgsql_tx_begun_total", map[string]string{"pool": "default"}, 1)
+	e.AddCounter("pgsql_tx_begun_total", map[string]string{"pool": "default"}, 1)
+	e.ObserveHistogram("pgsql_tx_duration_seconds", nil, 0.5)
+
+	assert.Equal(t, "2", e.vars.Get(`pgsql_tx_begun_total{pool="default"}`).String())
+	assert.Equal(t, "1", e.vars.Get("pgsql_tx_duration_seconds_count").String())
+	assert.Equal(t, "0.5", e.vars.Get("pgsql_tx_duration_seconds_sum").String())
+}
@@ -0,0 +1,185 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// DefaultBuckets are the upper bounds of the histogram buckets, in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type metricType string
+
+const (
+	typeGauge     metricType = "gauge"
+	typeCounter   metricType = "counter"
+	typeHistogram metricType = "histogram"
+)
+
+// Prometheus is a pgxpool.MetricsSink that serves the metrics over HTTP in the Prometheus text format.
+type Prometheus struct {
+	mu      sync.Mutex
+	buckets []float64
+	metrics map[string]*metric
+}
+
+// metric holds the series of a single metric, keyed by their formatted labels.
+type metric struct {
+	typ    metricType
+	series map[string]*series
+}
+
+// series holds the value of a gauge or a counter, or the state of a histogram.
+type series struct {
+	value  float64
+	counts []uint64
+	count  uint64
+}
+
+// NewPrometheus creates a Prometheus sink. Histograms use the given bucket upper bounds,
+// or DefaultBuckets when none are given.
+func NewPrometheus(buckets ...float64) *Prometheus {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	return &Prometheus{
+		buckets: buckets,
+		metrics: make(map[string]*metric),
+	}
+}
+
+// SetGauge implements pgxpool.MetricsSink.
+func (p *Prometheus) SetGauge(name string, labels map[string]string, value float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.series(name, typeGauge, labels).value = value
+}
+
+// AddCounter implements pgxpool.MetricsSink.
+func (p *Prometheus) AddCounter(name string, labels map[string]string, delta float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.series(name, typeCounter, labels).value += delta
+}
+
+// ObserveHistogram implements pgxpool.MetricsSink.
+func (p *Prometheus) ObserveHistogram(name string, labels map[string]string, value float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := p.series(name, typeHistogram, labels)
+	if s.counts == nil {
+		s.counts = make([]uint64, len(p.buckets))
+	}
+	for i, bound := range p.buckets {
+		if value <= bound {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.value += value
+}
+
+// series returns the series of the metric with the given labels, creating it if it does not exist yet.
+func (p *Prometheus) series(name string, typ metricType, labels map[string]string) *series {
+	m, ok := p.metrics[name]
+	if !ok {
+		m = &metric{typ: typ, series: make(map[string]*series)}
+		p.metrics[name] = m
+	}
+
+	key := formatLabels(labels)
+	s, ok := m.series[key]
+	if !ok {
+		s = new(series)
+		m.series[key] = s
+	}
+
+	return s
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format.
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	bw := bufio.NewWriter(w)
+	p.write(bw)
+	_ = bw.Flush()
+}
+
+// write writes the metrics sorted by name and labels.
+func (p *Prometheus) write(w *bufio.Writer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, name := range sortedKeys(p.metrics) {
+		m := p.metrics[name]
+		fmt.Fprintf(w, "# TYPE %s %s\n", name, m.typ)
+
+		for _, labels := range sortedKeys(m.series) {
+			s := m.series[labels]
+			if m.typ != typeHistogram {
+				writeSample(w, name, labels, s.value)
+				continue
+			}
+
+			for i, bound := range p.buckets {
+				writeSample(w, name+"_bucket", withLabel(labels, "le", formatFloat(bound)), float64(s.counts[i]))
+			}
+			writeSample(w, name+"_bucket", withLabel(labels, "le", "+Inf"), float64(s.count))
+			writeSample(w, name+"_sum", labels, s.value)
+			writeSample(w, name+"_count", labels, float64(s.count))
+		}
+	}
+}
+
+func writeSample(w *bufio.Writer, name, labels string, value float64) {
+	w.WriteString(name)
+	if labels != "" {
+		w.WriteByte('{')
+		w.WriteString(labels)
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+// withLabel appends a label to the formatted labels.
+func withLabel(labels, name, value string) string {
+	label := name + `="` + labelEscaper.Replace(value) + `"`
+	if labels == "" {
+		return label
+	}
+	return labels + "," + label
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
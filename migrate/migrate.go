@@ -5,11 +5,14 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"io/fs"
+	"strings"
 	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 )
 
 const (
@@ -37,6 +40,9 @@ type Config struct {
 	Scheme          string
 	Table           string
 	ConnMaxLifetime time.Duration
+	// FS is the source of the migrations, such as an embed.FS. When set,
+	// PathMigration is the directory of the migrations inside it.
+	FS fs.FS
 }
 
 type Migrate struct {
@@ -153,9 +159,18 @@ func (m *Migrate) newMigrationInstance(ctx context.Context) (_ *migrate.Migrate,
 		return nil, fmt.Errorf("failed to create migration driver: %w", err)
 	}
 
-	mig, err := migrate.NewWithDatabaseInstance(
-		fmt.Sprintf("file://%s", m.config.PathMigration), m.config.DatabaseName, driver,
-	)
+	var mig *migrate.Migrate
+	if m.config.FS != nil {
+		src, srcErr := iofs.New(m.config.FS, m.config.PathMigration)
+		if srcErr != nil {
+			return nil, fmt.Errorf("failed to open migration source: %w", srcErr)
+		}
+		mig, err = migrate.NewWithInstance("iofs", src, m.config.DatabaseName, driver)
+	} else {
+		mig, err = migrate.NewWithDatabaseInstance(
+			fmt.Sprintf("file://%s", m.config.PathMigration), m.config.DatabaseName, driver,
+		)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create migration instance: %w", err)
 	}
@@ -173,8 +188,12 @@ func (m *Migrate) checkMigrationTable(db *sql.DB) error {
 	switch {
 	case errors.Is(err, sql.ErrNoRows):
 		// Table not found, create it
-		query = "CREATE TABLE $1.$2 (version bigint not null primary key, dirty boolean not null)"
-		_, err = db.Exec(query, m.config.Scheme, m.config.Table)
+		// Identifiers can't be passed as parameters, so they are quoted into the query
+		query = fmt.Sprintf(
+			"CREATE TABLE %s.%s (version bigint not null primary key, dirty boolean not null)",
+			quoteIdentifier(m.config.Scheme), quoteIdentifier(m.config.Table),
+		)
+		_, err = db.Exec(query)
 		if err != nil {
 			return fmt.Errorf("failed to create migration table: %w", err)
 		}
@@ -187,3 +206,8 @@ func (m *Migrate) checkMigrationTable(db *sql.DB) error {
 		return nil
 	}
 }
+
+// quoteIdentifier quotes an SQL identifier, escaping the double quotes in it.
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,28 @@
+package outbox
+
+import (
+	"embed"
+
+	"github.com/i4erkasov/go-pgsql/migrate"
+)
+
+const defaultMigrationTable = "outbox_migration"
+
+// Migrations holds the migrations creating the outbox table, in the migrations directory.
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
+
+// NewMigrator creates a migrate.Migrate applying the outbox migrations with the given config.
+// Its version is tracked in its own table, "outbox_migration" unless config.Table is set,
+// so that it does not interfere with the migrations of the application.
+func NewMigrator(config migrate.Config) (*migrate.Migrate, error) {
+	config.FS = Migrations
+	config.PathMigration = "migrations"
+	if config.Table == "" {
+		config.Table = defaultMigrationTable
+	}
+	migrate.SetDefaultConfigValues(&config)
+
+	return migrate.NewWithConfig(config)
+}
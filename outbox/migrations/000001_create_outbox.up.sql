@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS outbox
+(
+    id            bigserial PRIMARY KEY,
+    aggregate_key text        NOT NULL,
+    topic         text        NOT NULL,
+    payload       bytea       NOT NULL,
+    headers       jsonb       NOT NULL DEFAULT '{}',
+    created_at    timestamptz NOT NULL DEFAULT now(),
+    published_at  timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox (aggregate_key, id) WHERE published_at IS NULL;
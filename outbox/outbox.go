@@ -0,0 +1,58 @@
+// Package outbox implements the transactional outbox pattern on top of pgx.TxManager:
+// messages are enqueued in the transaction of the business change, and a Relay
+// publishes them once the transaction has committed.
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/i4erkasov/go-pgsql/pgx"
+)
+
+// Message is a message stored in the outbox table.
+type Message struct {
+	// ID is assigned on Enqueue and orders the messages.
+	ID int64
+	// AggregateKey groups the messages that must be published in the order they were enqueued,
+	// such as the ID of the entity they are about.
+	AggregateKey string
+	Topic        string
+	Payload      []byte
+	Headers      map[string]string
+	// CreatedAt is assigned on Enqueue.
+	CreatedAt time.Time
+}
+
+// Outbox enqueues messages into the outbox table.
+type Outbox struct {
+	txManager pgx.TxManager
+}
+
+// New is Outbox constructor.
+func New(txManager pgx.TxManager) *Outbox {
+	return &Outbox{txManager: txManager}
+}
+
+// Enqueue inserts the messages into the outbox table. Called inside WithTx, it joins
+// the ongoing transaction, so that the messages are stored only if it commits.
+// Otherwise, the messages are inserted in a transaction of their own.
+func (o *Outbox) Enqueue(ctx context.Context, msgs ...Message) error {
+	return o.txManager.WithTx(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		for _, msg := range msgs {
+			headers := msg.Headers
+			if headers == nil {
+				headers = map[string]string{}
+			}
+
+			_, err := tx.Exec(ctx,
+				"INSERT INTO outbox (aggregate_key, topic, payload, headers) VALUES ($1, $2, $3, $4)",
+				msg.AggregateKey, msg.Topic, msg.Payload, headers,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
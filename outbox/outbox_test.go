@@ -0,0 +1,138 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/i4erkasov/go-pgsql/pgx"
+	"github.com/jackc/pgconn"
+	jackc "github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+// fakeRows returns the given messages as the rows of the fetch query.
+type fakeRows struct {
+	jackc.Rows
+	msgs []Message
+	next int
+}
+
+func (r *fakeRows) Next() bool {
+	r.next++
+	return r.next <= len(r.msgs)
+}
+
+func (r *fakeRows) Scan(dest ...interface{}) error {
+	msg := r.msgs[r.next-1]
+	*dest[0].(*int64) = msg.ID
+	*dest[1].(*string) = msg.AggregateKey
+	*dest[2].(*string) = msg.Topic
+	*dest[3].(*[]byte) = msg.Payload
+	*dest[4].(*map[string]string) = msg.Headers
+	*dest[5].(*time.Time) = msg.CreatedAt
+	return nil
+}
+
+func (r *fakeRows) Err() error { return nil }
+
+func (r *fakeRows) Close() {}
+
+// publisherFunc is a Publisher calling the function.
+type publisherFunc func(ctx context.Context, msgs []Message) error
+
+func (f publisherFunc) Publish(ctx context.Context, msgs []Message) error {
+	return f(ctx, msgs)
+}
+
+// OutboxTestSuite defines the structure for the test suite.
+type OutboxTestSuite struct {
+	suite.Suite
+}
+
+// TestEnqueue checks that the messages are inserted in the ongoing transaction.
+func (t *OutboxTestSuite) TestEnqueue() {
+	connMock := new(pgx.ConnMock)
+	txMock := new(pgx.TxMock)
+
+	connMock.On("Begin", mock.Anything).Return(txMock, nil).Once()
+	txMock.On("Exec", mock.Anything, "INSERT INTO outbox (aggregate_key, topic, payload, headers) VALUES ($1, $2, $3, $4)",
+		"order:1", "order.created", []byte(`{}`), map[string]string{}).Return(pgconn.CommandTag("INSERT 0 1"), nil).Once()
+	txMock.On("Exec", mock.Anything, "INSERT INTO outbox (aggregate_key, topic, payload, headers) VALUES ($1, $2, $3, $4)",
+		"order:1", "order.paid", []byte(`{}`), map[string]string{"trace": "1"}).Return(pgconn.CommandTag("INSERT 0 1"), nil).Once()
+	txMock.On("Commit", mock.Anything).Return(nil).Once()
+
+	txManager := pgx.NewTxManagerFromConn(connMock)
+	ob := New(txManager)
+
+	err := txManager.WithTx(context.Background(), func(ctx context.Context, tx pgx.Tx) error {
+		return ob.Enqueue(ctx,
+			Message{AggregateKey: "order:1", Topic: "order.created", Payload: []byte(`{}`)},
+			Message{AggregateKey: "order:1", Topic: "order.paid", Payload: []byte(`{}`), Headers: map[string]string{"trace": "1"}},
+		)
+	})
+
+	t.NoError(err)
+	connMock.AssertExpectations(t.T())
+	txMock.AssertExpectations(t.T())
+}
+
+// TestRelayOnce checks that a fetched batch is published in order and marked as published.
+func (t *OutboxTestSuite) TestRelayOnce() {
+	connMock := new(pgx.ConnMock)
+	txMock := new(pgx.TxMock)
+	msgs := []Message{
+		{ID: 1, AggregateKey: "order:1", Topic: "order.created"},
+		{ID: 3, AggregateKey: "order:1", Topic: "order.paid"},
+	}
+
+	connMock.On("Begin", mock.Anything).Return(txMock, nil)
+	txMock.On("Query", mock.Anything, fetchQuery, 10).Return(&fakeRows{msgs: msgs}, nil)
+	txMock.On("Exec", mock.Anything, "DELETE FROM outbox WHERE id = ANY($1)", []int64{1, 3}).Return(pgconn.CommandTag("DELETE 2"), nil)
+	txMock.On("Commit", mock.Anything).Return(nil)
+
+	var published []Message
+	publisher := publisherFunc(func(ctx context.Context, msgs []Message) error {
+		published = msgs
+		return nil
+	})
+	relay := NewRelay(pgx.NewTxManagerFromConn(connMock), publisher, WithBatchSize(10), WithDelete())
+
+	n, err := relay.RelayOnce(context.Background())
+
+	t.NoError(err)
+	t.Equal(2, n)
+	t.Equal(msgs, published)
+	connMock.AssertExpectations(t.T())
+	txMock.AssertExpectations(t.T())
+}
+
+// TestRelayOncePublishError checks that the batch stays pending when it fails to be published.
+func (t *OutboxTestSuite) TestRelayOncePublishError() {
+	connMock := new(pgx.ConnMock)
+	txMock := new(pgx.TxMock)
+	publishErr := errors.New("broker is down")
+
+	connMock.On("Begin", mock.Anything).Return(txMock, nil)
+	txMock.On("Query", mock.Anything, fetchQuery, defaultBatchSize).Return(&fakeRows{msgs: []Message{{ID: 1}}}, nil)
+	txMock.On("Rollback", mock.Anything).Return(nil)
+
+	relay := NewRelay(pgx.NewTxManagerFromConn(connMock), publisherFunc(func(context.Context, []Message) error {
+		return publishErr
+	}))
+
+	n, err := relay.RelayOnce(context.Background())
+
+	t.ErrorIs(err, publishErr)
+	t.Equal(0, n)
+	connMock.AssertExpectations(t.T())
+	txMock.AssertExpectations(t.T())
+	txMock.AssertNotCalled(t.T(), "Exec", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// TestOutboxSuite runs the test suite.
+func TestOutboxSuite(t *testing.T) {
+	suite.Run(t, new(OutboxTestSuite))
+}
@@ -0,0 +1,191 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/i4erkasov/go-pgsql/pgx"
+)
+
+const (
+	defaultBatchSize    = 100
+	defaultPollInterval = time.Second
+
+	// relayLockClass namespaces the advisory locks the relays take on the aggregate keys.
+	relayLockClass = 0x0b0c
+)
+
+// fetchQuery locks a batch of pending messages. An aggregate key is processed by a single relay
+// at a time: the relay takes a transaction-scoped advisory lock on each key it picks, and the keys
+// locked by other relays are skipped, so that the messages of a key are published in order.
+var fetchQuery = fmt.Sprintf(`WITH keys AS (
+    SELECT aggregate_key
+    FROM outbox
+    WHERE published_at IS NULL
+    GROUP BY aggregate_key
+    ORDER BY min(id)
+    LIMIT $1
+), locked AS (
+    SELECT aggregate_key FROM keys WHERE pg_try_advisory_xact_lock(%d, hashtext(aggregate_key))
+)
+SELECT o.id, o.aggregate_key, o.topic, o.payload, o.headers, o.created_at
+FROM outbox o
+JOIN locked USING (aggregate_key)
+WHERE o.published_at IS NULL
+ORDER BY o.id
+LIMIT $1
+FOR UPDATE OF o SKIP LOCKED`, relayLockClass)
+
+// Publisher delivers the messages to the message broker.
+type Publisher interface {
+	// Publish delivers the messages in the given order. If it returns an error,
+	// the whole batch is published again later, so delivery is at least once.
+	Publish(ctx context.Context, msgs []Message) error
+}
+
+// Relay polls the outbox table on the master pool and hands the pending messages to the Publisher.
+type Relay struct {
+	txManager    pgx.TxManager
+	publisher    Publisher
+	batchSize    int
+	pollInterval time.Duration
+	delete       bool
+	onError      func(context.Context, error)
+}
+
+// RelayOption defines the type for functional options for Relay.
+type RelayOption func(*Relay)
+
+// WithBatchSize is an option to set the maximum number of messages published at once (default: 100).
+func WithBatchSize(size int) RelayOption {
+	return func(r *Relay) {
+		r.batchSize = size
+	}
+}
+
+// WithPollInterval is an option to set the delay between polls once the outbox is drained (default: 1s).
+func WithPollInterval(interval time.Duration) RelayOption {
+	return func(r *Relay) {
+		r.pollInterval = interval
+	}
+}
+
+// WithDelete is an option to delete the published messages instead of marking them as published.
+func WithDelete() RelayOption {
+	return func(r *Relay) {
+		r.delete = true
+	}
+}
+
+// WithErrorHandler is an option to handle the errors of Run. By default, they are logged with log.Printf.
+func WithErrorHandler(handler func(context.Context, error)) RelayOption {
+	return func(r *Relay) {
+		r.onError = handler
+	}
+}
+
+// NewRelay is Relay constructor.
+func NewRelay(txManager pgx.TxManager, publisher Publisher, opts ...RelayOption) *Relay {
+	r := &Relay{
+		txManager:    txManager,
+		publisher:    publisher,
+		batchSize:    defaultBatchSize,
+		pollInterval: defaultPollInterval,
+		onError: func(_ context.Context, err error) {
+			log.Printf("outbox relay: %v", err)
+		},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Run relays the messages until the context is done. While full batches are found,
+// the next one is fetched right away; otherwise, the relay waits for the poll interval.
+func (r *Relay) Run(ctx context.Context) {
+	for {
+		n, err := r.RelayOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.onError(ctx, err)
+		}
+		if err == nil && n == r.batchSize {
+			continue
+		}
+
+		timer := time.NewTimer(r.pollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// RelayOnce publishes a single batch of pending messages and returns the number of messages published.
+// The batch is locked, published and marked in one transaction: if any step fails, the transaction
+// is rolled back and the messages stay pending.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	var n int
+	err := r.txManager.WithTx(ctx, func(ctx context.Context, tx pgx.Tx) error {
+		msgs, err := r.fetch(ctx, tx)
+		if err != nil || len(msgs) == 0 {
+			return err
+		}
+
+		if err = r.publisher.Publish(ctx, msgs); err != nil {
+			return fmt.Errorf("failed to publish outbox messages: %w", err)
+		}
+
+		ids := make([]int64, len(msgs))
+		for i, msg := range msgs {
+			ids[i] = msg.ID
+		}
+		if err = r.mark(ctx, tx, ids); err != nil {
+			return err
+		}
+
+		n = len(msgs)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// fetch locks the next batch of pending messages.
+func (r *Relay) fetch(ctx context.Context, tx pgx.Tx) ([]Message, error) {
+	rows, err := tx.Query(ctx, fetchQuery, r.batchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var msgs []Message
+	for rows.Next() {
+		var msg Message
+		if err = rows.Scan(&msg.ID, &msg.AggregateKey, &msg.Topic, &msg.Payload, &msg.Headers, &msg.CreatedAt); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+
+	return msgs, rows.Err()
+}
+
+// mark marks the published messages, or deletes them when configured with WithDelete.
+func (r *Relay) mark(ctx context.Context, tx pgx.Tx, ids []int64) error {
+	query := "UPDATE outbox SET published_at = now() WHERE id = ANY($1)"
+	if r.delete {
+		query = "DELETE FROM outbox WHERE id = ANY($1)"
+	}
+
+	_, err := tx.Exec(ctx, query, ids)
+	return err
+}
@@ -0,0 +1,91 @@
+package pgx
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"time"
+)
+
+const (
+	minLockPollInterval = 5 * time.Millisecond
+	maxLockPollInterval = 100 * time.Millisecond
+)
+
+// ErrLockNotAcquired is the error used when a try-lock finds the advisory lock held by another transaction.
+var ErrLockNotAcquired = errors.New("advisory lock not acquired")
+
+// AdvisoryLock identifies a transaction-scoped advisory lock and how it is acquired.
+type AdvisoryLock struct {
+	// Key identifies the lock. Use LockKey to derive it from a string.
+	Key int64
+	// Try acquires the lock with pg_try_advisory_xact_lock instead of waiting for it,
+	// and fails with ErrLockNotAcquired while it is held by another transaction.
+	Try bool
+	// Timeout is how long a try-lock keeps trying before giving up. Zero tries once.
+	Timeout time.Duration
+}
+
+// LockKey hashes s to an advisory lock key with 64-bit FNV-1a,
+// so that the same string always maps to the same lock.
+func LockKey(s string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+	return int64(h.Sum64())
+}
+
+// WithAdvisoryLock executes a function within a transaction holding the given advisory lock.
+// The lock is taken with pg_advisory_xact_lock, or pg_try_advisory_xact_lock when lock.Try is set,
+// in the ongoing transaction or savepoint if there is one, and in a new transaction otherwise.
+// It is released when the transaction ends, or when the savepoint it was taken in is rolled back;
+// taking a lock the transaction already holds succeeds immediately.
+func (t *Transactor) WithAdvisoryLock(ctx context.Context, lock AdvisoryLock, tFunc func(context.Context, Tx) error) error {
+	return t.WithTx(ctx, func(ctx context.Context, tx Tx) error {
+		if err := acquireAdvisoryLock(ctx, tx, lock); err != nil {
+			return err
+		}
+		return tFunc(ctx, tx)
+	})
+}
+
+// acquireAdvisoryLock takes the lock in tx, polling a try-lock until it succeeds or its timeout expires.
+func acquireAdvisoryLock(ctx context.Context, tx Tx, lock AdvisoryLock) error {
+	if !lock.Try {
+		_, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", lock.Key)
+		return err
+	}
+
+	var (
+		deadline = time.Now().Add(lock.Timeout)
+		interval = minLockPollInterval
+	)
+	for {
+		var acquired bool
+		if err := tx.QueryRow(ctx, "SELECT pg_try_advisory_xact_lock($1)", lock.Key).Scan(&acquired); err != nil {
+			return err
+		}
+		if acquired {
+			return nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return ErrLockNotAcquired
+		}
+		if interval > remaining {
+			interval = remaining
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(ErrLockNotAcquired, ctx.Err())
+		case <-timer.C:
+		}
+
+		if interval *= 2; interval > maxLockPollInterval {
+			interval = maxLockPollInterval
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package pgx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+)
+
+var (
+	// ErrNoColumns is the error used when the columns to copy can't be determined.
+	ErrNoColumns = errors.New("no columns to copy")
+
+	// ErrUpsertUnsupported is the error used when an upsert is requested on a Querier that can't start a transaction.
+	ErrUpsertUnsupported = errors.New("upsert requires a connection pool or a transaction")
+)
+
+// CopyFromSource is an alias to pgx.CopyFromSource
+type CopyFromSource = pgx.CopyFromSource
+
+// copyConfig holds the options of a bulk import.
+type copyConfig struct {
+	upsert          bool
+	conflictColumns []string
+	updateColumns   []string
+}
+
+// CopyOption defines the type for functional options for the bulk import helpers.
+type CopyOption func(*copyConfig)
+
+// WithUpsert is an option to merge the rows into the table instead of inserting them: they are copied into
+// a temporary table, then inserted with INSERT ... ON CONFLICT (conflictColumns) DO UPDATE, overwriting
+// the other copied columns of the existing rows.
+func WithUpsert(conflictColumns ...string) CopyOption {
+	return func(c *copyConfig) {
+		c.upsert = true
+		c.conflictColumns = conflictColumns
+	}
+}
+
+// WithUpdateColumns is an option to restrict the columns an upsert overwrites. Without any, conflicting rows are left as they are.
+func WithUpdateColumns(columns ...string) CopyOption {
+	return func(c *copyConfig) {
+		if columns == nil {
+			columns = []string{}
+		}
+		c.updateColumns = columns
+	}
+}
+
+// CopyFrom bulk loads the rows of src into the columns of table with the COPY protocol and returns the number
+// of rows copied. q is a connection pool or a transaction, such as the one returned by TxManager.Querier.
+// The table name may be qualified with its schema, as in "public.users".
+func CopyFrom(ctx context.Context, q Querier, table string, columns []string, src CopyFromSource, opts ...CopyOption) (int64, error) {
+	if len(columns) == 0 {
+		return 0, ErrNoColumns
+	}
+
+	var config copyConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	if !config.upsert {
+		return q.CopyFrom(ctx, pgx.Identifier(strings.Split(table, ".")), columns, src)
+	}
+	return upsert(ctx, q, table, columns, src, config)
+}
+
+// CopyFromStructs bulk loads a slice of structs into table. The columns are derived from the fields of T,
+// named by their db tags. See CopyFrom.
+func CopyFromStructs[T any](ctx context.Context, q Querier, table string, rows []T, opts ...CopyOption) (int64, error) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() != reflect.Struct {
+		return 0, fmt.Errorf("%w: %v is not a struct", ErrNoColumns, typ)
+	}
+
+	fields := structFields(typ)
+	columns := make([]string, len(fields))
+	for i, f := range fields {
+		columns[i] = f.column
+	}
+
+	return CopyFrom(ctx, q, table, columns, &structSource[T]{rows: rows, fields: fields, next: -1}, opts...)
+}
+
+// CopyFromCSV bulk loads CSV data into table. The first record is the header naming the columns
+// the fields are copied into, so its order does not have to match the table. Empty fields are
+// copied as NULL; the other fields are converted from text to the types of their columns. See CopyFrom.
+func CopyFromCSV(ctx context.Context, q Querier, table string, r io.Reader, opts ...CopyOption) (int64, error) {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return 0, ErrNoColumns
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	columns := make([]string, len(header))
+	for i, name := range header {
+		columns[i] = strings.TrimSpace(name)
+	}
+
+	return CopyFrom(ctx, q, table, columns, &csvSource{reader: reader, values: make([]any, len(columns))}, opts...)
+}
+
+// upsert copies the rows into a temporary table and merges them into table.
+func upsert(ctx context.Context, q Querier, table string, columns []string, src CopyFromSource, config copyConfig) (n int64, err error) {
+	beginner, ok := q.(interface {
+		Begin(ctx context.Context) (Tx, error)
+	})
+	if !ok {
+		return 0, ErrUpsertUnsupported
+	}
+	if len(config.conflictColumns) == 0 {
+		return 0, fmt.Errorf("%w: no conflict columns", ErrUpsertUnsupported)
+	}
+
+	// A transaction on a pool, or a savepoint in a transaction, so that the temporary table
+	// lives on the same connection as the merge
+	tx, err := beginner.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+			return
+		}
+		err = tx.Commit(ctx)
+	}()
+
+	suffix := make([]byte, 8)
+	if _, err = rand.Read(suffix); err != nil {
+		return 0, err
+	}
+	tmp := "pgx_copy_" + hex.EncodeToString(suffix)
+
+	target := pgx.Identifier(strings.Split(table, ".")).Sanitize()
+	cols := sanitizeColumns(columns)
+	_, err = tx.Exec(ctx, fmt.Sprintf("CREATE TEMPORARY TABLE %s ON COMMIT DROP AS SELECT %s FROM %s WITH NO DATA",
+		tmp, strings.Join(cols, ", "), target))
+	if err != nil {
+		return 0, err
+	}
+
+	if n, err = tx.CopyFrom(ctx, pgx.Identifier{tmp}, columns, src); err != nil {
+		return 0, err
+	}
+
+	if _, err = tx.Exec(ctx, mergeSQL(target, tmp, columns, config)); err != nil {
+		return 0, err
+	}
+
+	// Dropped right away, as a savepoint leaves it to the end of the enclosing transaction
+	_, err = tx.Exec(ctx, "DROP TABLE "+tmp)
+	return n, err
+}
+
+// mergeSQL returns the statement merging the rows of the temporary table into the target table.
+func mergeSQL(target, tmp string, columns []string, config copyConfig) string {
+	cols := strings.Join(sanitizeColumns(columns), ", ")
+
+	update := config.updateColumns
+	if update == nil {
+		conflict := make(map[string]bool, len(config.conflictColumns))
+		for _, c := range config.conflictColumns {
+			conflict[c] = true
+		}
+		for _, c := range columns {
+			if !conflict[c] {
+				update = append(update, c)
+			}
+		}
+	}
+
+	action := "DO NOTHING"
+	if len(update) > 0 {
+		set := make([]string, len(update))
+		for i, c := range sanitizeColumns(update) {
+			set[i] = c + " = EXCLUDED." + c
+		}
+		action = "DO UPDATE SET " + strings.Join(set, ", ")
+	}
+
+	return fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s ON CONFLICT (%s) %s",
+		target, cols, cols, tmp, strings.Join(sanitizeColumns(config.conflictColumns), ", "), action)
+}
+
+// sanitizeColumns quotes the column names.
+func sanitizeColumns(columns []string) []string {
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = pgx.Identifier{c}.Sanitize()
+	}
+	return quoted
+}
+
+// structSource is a CopyFromSource over a slice of structs.
+type structSource[T any] struct {
+	rows   []T
+	fields []structField
+	next   int
+}
+
+func (s *structSource[T]) Next() bool {
+	s.next++
+	return s.next < len(s.rows)
+}
+
+func (s *structSource[T]) Values() ([]any, error) {
+	v := reflect.ValueOf(&s.rows[s.next]).Elem()
+	values := make([]any, len(s.fields))
+	for i, f := range s.fields {
+		// The fields of a nil embedded pointer are copied as NULL.
+		if field := fieldByIndex(v, f.index, false); field.IsValid() {
+			values[i] = field.Interface()
+		}
+	}
+	return values, nil
+}
+
+func (s *structSource[T]) Err() error {
+	return nil
+}
+
+// csvSource is a CopyFromSource over the records of a CSV reader.
+type csvSource struct {
+	reader *csv.Reader
+	values []any
+	err    error
+}
+
+func (s *csvSource) Next() bool {
+	record, err := s.reader.Read()
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			s.err = err
+		}
+		return false
+	}
+	if len(record) != len(s.values) {
+		s.err = fmt.Errorf("csv record has %d fields, the header has %d", len(record), len(s.values))
+		return false
+	}
+
+	for i, field := range record {
+		if field == "" {
+			s.values[i] = nil
+		} else {
+			s.values[i] = field
+		}
+	}
+	return true
+}
+
+func (s *csvSource) Values() ([]any, error) {
+	return s.values, nil
+}
+
+func (s *csvSource) Err() error {
+	return s.err
+}
@@ -0,0 +1,147 @@
+package pgx
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type copyBase struct {
+	ID int64 `db:"id"`
+}
+
+type copyUser struct {
+	copyBase
+	Name    string  `db:"name"`
+	Email   *string `db:"email"`
+	Age     int
+	Ignored string `db:"-"`
+	secret  string
+}
+
+// collectRows reads all the rows of a CopyFromSource.
+func collectRows(src pgx.CopyFromSource) ([][]any, error) {
+	var rows [][]any
+	for src.Next() {
+		values, err := src.Values()
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, append([]any(nil), values...))
+	}
+	return rows, src.Err()
+}
+
+// CopyFromTestSuite defines the structure for the test suite.
+type CopyFromTestSuite struct {
+	suite.Suite
+}
+
+// TestCopyFromStructs checks that the columns are derived from the fields of the struct.
+func (t *CopyFromTestSuite) TestCopyFromStructs() {
+	txMock := new(TxMock)
+	email := "john@example.com"
+
+	var rows [][]any
+	txMock.On("CopyFrom", mock.Anything, pgx.Identifier{"public", "users"}, []string{"id", "name", "email", "age"}, mock.Anything).
+		Run(func(args mock.Arguments) {
+			rows, _ = collectRows(args.Get(3).(pgx.CopyFromSource))
+		}).Return(int64(2), nil)
+
+	n, err := CopyFromStructs(context.Background(), txMock, "public.users", []copyUser{
+		{copyBase: copyBase{ID: 1}, Name: "John Doe", Email: &email, Age: 42, secret: "x"},
+		{copyBase: copyBase{ID: 2}, Name: "Jane Doe"},
+	})
+
+	t.NoError(err)
+	t.Equal(int64(2), n)
+	t.Equal([][]any{
+		{int64(1), "John Doe", &email, 42},
+		{int64(2), "Jane Doe", (*string)(nil), 0},
+	}, rows)
+	txMock.AssertExpectations(t.T())
+}
+
+// TestCopyFromStructsEmbeddedPointer checks that the fields of a nil embedded pointer are copied as NULL.
+func (t *CopyFromTestSuite) TestCopyFromStructsEmbeddedPointer() {
+	txMock := new(TxMock)
+
+	var rows [][]any
+	txMock.On("CopyFrom", mock.Anything, pgx.Identifier{"posts"}, []string{"created_by", "title"}, mock.Anything).
+		Run(func(args mock.Arguments) {
+			rows, _ = collectRows(args.Get(3).(pgx.CopyFromSource))
+		}).Return(int64(2), nil)
+
+	_, err := CopyFromStructs(context.Background(), txMock, "posts", []queryPost{
+		{QueryAudit: &QueryAudit{CreatedBy: "john"}, Title: "Hello"},
+		{Title: "Anonymous"},
+	})
+
+	t.NoError(err)
+	t.Equal([][]any{{"john", "Hello"}, {nil, "Anonymous"}}, rows)
+	txMock.AssertExpectations(t.T())
+}
+
+// TestCopyFromCSV checks that the header maps the fields to the columns and empty fields are NULL.
+func (t *CopyFromTestSuite) TestCopyFromCSV() {
+	txMock := new(TxMock)
+
+	var rows [][]any
+	txMock.On("CopyFrom", mock.Anything, pgx.Identifier{"users"}, []string{"name", "id"}, mock.Anything).
+		Run(func(args mock.Arguments) {
+			rows, _ = collectRows(args.Get(3).(pgx.CopyFromSource))
+		}).Return(int64(2), nil)
+
+	n, err := CopyFromCSV(context.Background(), txMock, "users", strings.NewReader("name, id\nJohn Doe,1\n,2\n"))
+
+	t.NoError(err)
+	t.Equal(int64(2), n)
+	t.Equal([][]any{{"John Doe", "1"}, {nil, "2"}}, rows)
+	txMock.AssertExpectations(t.T())
+}
+
+// TestCopyFromUpsert checks that the rows are copied into a temporary table and merged in a savepoint.
+func (t *CopyFromTestSuite) TestCopyFromUpsert() {
+	txMock := new(TxMock)
+	spMock := new(TxMock)
+	tag := pgconn.CommandTag("OK")
+
+	var tmp string
+	txMock.On("Begin", mock.Anything).Return(spMock, nil)
+	spMock.On("Exec", mock.Anything, mock.MatchedBy(func(sql string) bool {
+		if !strings.HasPrefix(sql, "CREATE TEMPORARY TABLE pgx_copy_") {
+			return false
+		}
+		tmp = strings.Fields(sql)[3]
+		return strings.HasSuffix(sql, ` ON COMMIT DROP AS SELECT "id", "name" FROM "users" WITH NO DATA`)
+	})).Return(tag, nil).Once()
+	spMock.On("CopyFrom", mock.Anything, mock.MatchedBy(func(table pgx.Identifier) bool {
+		return len(table) == 1 && table[0] == tmp
+	}), []string{"id", "name"}, mock.Anything).Return(int64(1), nil)
+	spMock.On("Exec", mock.Anything, mock.MatchedBy(func(sql string) bool {
+		return sql == `INSERT INTO "users" ("id", "name") SELECT "id", "name" FROM `+tmp+
+			` ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name"`
+	})).Return(tag, nil).Once()
+	spMock.On("Exec", mock.Anything, mock.MatchedBy(func(sql string) bool {
+		return sql == "DROP TABLE "+tmp
+	})).Return(tag, nil).Once()
+	spMock.On("Commit", mock.Anything).Return(nil)
+
+	n, err := CopyFrom(context.Background(), txMock, "users", []string{"id", "name"},
+		pgx.CopyFromRows([][]any{{1, "John Doe"}}), WithUpsert("id"))
+
+	t.NoError(err)
+	t.Equal(int64(1), n)
+	txMock.AssertExpectations(t.T())
+	spMock.AssertExpectations(t.T())
+}
+
+// TestCopyFromSuite runs the test suite.
+func TestCopyFromSuite(t *testing.T) {
+	suite.Run(t, new(CopyFromTestSuite))
+}
@@ -0,0 +1,157 @@
+package pgx
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+)
+
+// HookPanicError reports a panic that occurred in a commit or rollback hook.
+type HookPanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *HookPanicError) Error() string {
+	return fmt.Sprintf("panic in transaction hook: %v", e.Value)
+}
+
+// txHooks holds the callbacks registered for a transaction or one of its savepoints.
+type txHooks struct {
+	sync.Mutex
+	parent   *txHooks
+	commit   []func(context.Context)
+	rollback []func(context.Context, error)
+}
+
+// txHooksKey is used for storing the hooks of the active transaction in the context.
+// It is namespaced by the scope that owns the transaction, the connection of a Transactor
+// or a Coordinator, so that the hooks of a manager are never handed over to another one.
+// The zero key holds the hooks of the innermost transaction of any scope, which OnCommit
+// and OnRollback register to.
+type txHooksKey struct {
+	scope any
+}
+
+// WithHookPanicHandler is an option to receive panics recovered from commit and rollback hooks.
+// By default, they are written to the standard logger.
+func WithHookPanicHandler(handler func(context.Context, error)) Option {
+	return func(t *Transactor) {
+		t.hookPanicked = handler
+	}
+}
+
+// OnCommit registers fn to be called after the outermost transaction in the context has been committed.
+// Hooks registered inside a savepoint are discarded when the savepoint is rolled back.
+func OnCommit(ctx context.Context, fn func(context.Context)) error {
+	hooks, ok := ctx.Value(txHooksKey{}).(*txHooks)
+	if !ok {
+		return ErrNoTransaction
+	}
+
+	hooks.Lock()
+	defer hooks.Unlock()
+	hooks.commit = append(hooks.commit, fn)
+
+	return nil
+}
+
+// OnRollback registers fn to be called after the outermost transaction in the context has been rolled back,
+// with the error that caused the rollback. Hooks registered inside a savepoint that is rolled back
+// are called with the error of the savepoint once the outermost transaction has ended, whatever its outcome.
+func OnRollback(ctx context.Context, fn func(context.Context, error)) error {
+	hooks, ok := ctx.Value(txHooksKey{}).(*txHooks)
+	if !ok {
+		return ErrNoTransaction
+	}
+
+	hooks.Lock()
+	defer hooks.Unlock()
+	hooks.rollback = append(hooks.rollback, fn)
+
+	return nil
+}
+
+// withTxHooks stores a new set of hooks for scope in the context, nested into the active one
+// of the same scope if any.
+func withTxHooks(ctx context.Context, scope any) (context.Context, *txHooks) {
+	parent, _ := ctx.Value(txHooksKey{scope: scope}).(*txHooks)
+	hooks := &txHooks{parent: parent}
+	ctx = context.WithValue(ctx, txHooksKey{scope: scope}, hooks)
+	return context.WithValue(ctx, txHooksKey{}, hooks), hooks
+}
+
+// hooks returns the hooks of the active transaction of the Transactor in the context.
+func (t *Transactor) hooks(ctx context.Context) *txHooks {
+	hooks, _ := ctx.Value(txHooksKey{scope: t.conn}).(*txHooks)
+	return hooks
+}
+
+// afterCommit runs the commit hooks of a committed transaction. For a released savepoint,
+// its hooks are handed over to the enclosing transaction instead.
+func (t *Transactor) afterCommit(parent context.Context, hooks *txHooks) {
+	if hooks == nil {
+		return
+	}
+
+	hooks.Lock()
+	commit, rollback := hooks.commit, hooks.rollback
+	hooks.Unlock()
+
+	if hooks.parent != nil {
+		hooks.parent.Lock()
+		hooks.parent.commit = append(hooks.parent.commit, commit...)
+		hooks.parent.rollback = append(hooks.parent.rollback, rollback...)
+		hooks.parent.Unlock()
+		return
+	}
+
+	for _, fn := range commit {
+		t.runHook(parent, func() { fn(parent) })
+	}
+}
+
+// afterRollback runs the rollback hooks of a rolled back transaction and discards its commit hooks.
+// For a rolled back savepoint, its rollback hooks are handed over to the enclosing transaction
+// instead, to run with err once it has ended, committed or not.
+func (t *Transactor) afterRollback(parent context.Context, hooks *txHooks, err error) {
+	if hooks == nil {
+		return
+	}
+
+	hooks.Lock()
+	rollback := hooks.rollback
+	hooks.Unlock()
+
+	if hooks.parent != nil {
+		hooks.parent.Lock()
+		for _, fn := range rollback {
+			hooks.parent.commit = append(hooks.parent.commit, func(ctx context.Context) { fn(ctx, err) })
+			hooks.parent.rollback = append(hooks.parent.rollback, func(ctx context.Context, _ error) { fn(ctx, err) })
+		}
+		hooks.parent.Unlock()
+		return
+	}
+
+	for _, fn := range rollback {
+		t.runHook(parent, func() { fn(parent, err) })
+	}
+}
+
+// runHook calls fn, isolating the transaction manager from its panics.
+func (t *Transactor) runHook(ctx context.Context, fn func()) {
+	defer func() {
+		if p := recover(); p != nil {
+			err := &HookPanicError{Value: p, Stack: debug.Stack()}
+			if t.hookPanicked != nil {
+				t.hookPanicked(ctx, err)
+				return
+			}
+			log.Printf("%v\n%s", err, err.Stack)
+		}
+	}()
+
+	fn()
+}
@@ -0,0 +1,54 @@
+package pgx
+
+import (
+	"time"
+
+	"github.com/i4erkasov/go-pgsql/pgxpool"
+)
+
+const (
+	metricTxBegun      = "pgsql_tx_begun_total"
+	metricTxCommitted  = "pgsql_tx_committed_total"
+	metricTxRolledBack = "pgsql_tx_rolled_back_total"
+	metricTxRetried    = "pgsql_tx_retried_total"
+	metricTxDuration   = "pgsql_tx_duration_seconds"
+)
+
+// MetricsSink is an alias to pgxpool.MetricsSink
+type MetricsSink = pgxpool.MetricsSink
+
+// WithMetrics is an option to count the transactions begun, committed, rolled back and retried
+// by the manager, and to observe their duration. Savepoints are not counted.
+func WithMetrics(sink MetricsSink) Option {
+	return func(t *Transactor) {
+		t.metrics = sink
+	}
+}
+
+// metricLabels returns the labels of the transaction metrics of the manager.
+func (t *Transactor) metricLabels() map[string]string {
+	return map[string]string{"pool": t.name}
+}
+
+// countTx increments the transaction counter with the given name.
+func (t *Transactor) countTx(name string) {
+	if t.metrics == nil {
+		return
+	}
+
+	t.metrics.AddCounter(name, t.metricLabels(), 1)
+}
+
+// observeTxEnd counts the end of an outermost transaction and observes its duration.
+func (t *Transactor) observeTxEnd(committed bool, duration time.Duration) {
+	if t.metrics == nil {
+		return
+	}
+
+	if committed {
+		t.countTx(metricTxCommitted)
+	} else {
+		t.countTx(metricTxRolledBack)
+	}
+	t.metrics.ObserveHistogram(metricTxDuration, t.metricLabels(), duration.Seconds())
+}
@@ -0,0 +1,304 @@
+package pgx
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// namedCacheSize is the number of rewritten queries kept by namedQueries.
+const namedCacheSize = 1024
+
+// namedQuery is a query with named parameters rewritten to positional ones.
+type namedQuery struct {
+	sql   string
+	names []string
+}
+
+// namedQueries caches the rewritten queries by query text.
+var namedQueries = newNamedQueryCache(namedCacheSize)
+
+// namedQueryCache is a cache of rewritten queries by query text, bounded to size queries:
+// the least recently used one is evicted, so that queries built dynamically do not make it grow without bound.
+type namedQueryCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // of *namedCacheEntry, the most recently used first
+	items map[string]*list.Element
+}
+
+type namedCacheEntry struct {
+	sql   string
+	query *namedQuery
+}
+
+func newNamedQueryCache(size int) *namedQueryCache {
+	return &namedQueryCache{size: size, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+// get returns the rewritten sql, parsed on first use.
+func (c *namedQueryCache) get(sql string) *namedQuery {
+	c.mu.Lock()
+	if el, ok := c.items[sql]; ok {
+		c.order.MoveToFront(el)
+		c.mu.Unlock()
+		return el.Value.(*namedCacheEntry).query
+	}
+	c.mu.Unlock()
+
+	query := parseNamed(sql)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[sql]; ok {
+		// Parsed concurrently
+		c.order.MoveToFront(el)
+		return el.Value.(*namedCacheEntry).query
+	}
+	c.items[sql] = c.order.PushFront(&namedCacheEntry{sql: sql, query: query})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*namedCacheEntry).sql)
+	}
+
+	return query
+}
+
+// NamedExec executes the statement with named parameters on q. See BindNamed.
+func NamedExec(ctx context.Context, q Querier, sql string, arg any) (pgconn.CommandTag, error) {
+	sql, args, err := BindNamed(sql, arg)
+	if err != nil {
+		return nil, err
+	}
+	return q.Exec(ctx, sql, args...)
+}
+
+// NamedQuery runs the query with named parameters on q. See BindNamed.
+func NamedQuery(ctx context.Context, q Querier, sql string, arg any) (pgx.Rows, error) {
+	sql, args, err := BindNamed(sql, arg)
+	if err != nil {
+		return nil, err
+	}
+	return q.Query(ctx, sql, args...)
+}
+
+// NamedQueryRow runs the query with named parameters on q. An error binding the parameters
+// is returned by the Scan of the row. See BindNamed.
+func NamedQueryRow(ctx context.Context, q Querier, sql string, arg any) pgx.Row {
+	sql, args, err := BindNamed(sql, arg)
+	if err != nil {
+		return errRow{err: err}
+	}
+	return q.QueryRow(ctx, sql, args...)
+}
+
+// errRow is a row failing to scan with err.
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(...interface{}) error {
+	return r.err
+}
+
+// BindNamed rewrites the :name and @name parameters of sql to positional parameters, and returns
+// their values taken from arg: a map with string keys, or a struct, or a pointer to a struct,
+// whose fields are mapped to the names like the columns of QueryOne. A name used several times
+// is bound once. The rewritten query is cached by query text, up to the 1024 most recently used queries.
+//
+// String literals, quoted identifiers, comments, dollar-quoted strings and :: casts are left alone.
+// The names start with a letter or an underscore, so that array slices with numeric bounds, such as
+// a[1:2], are not parameters.
+func BindNamed(sql string, arg any) (string, []any, error) {
+	nq := namedQueries.get(sql)
+
+	args := make([]any, len(nq.names))
+	for i, name := range nq.names {
+		value, err := namedValue(arg, name)
+		if err != nil {
+			return "", nil, err
+		}
+		args[i] = value
+	}
+
+	return nq.sql, args, nil
+}
+
+// namedValue returns the value of the parameter name in arg.
+func namedValue(arg any, name string) (any, error) {
+	v := reflect.ValueOf(arg)
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		value := v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+		if !value.IsValid() {
+			return nil, fmt.Errorf("named parameter %q has no value", name)
+		}
+		return value.Interface(), nil
+	case v.Kind() == reflect.Struct:
+		index, ok := fieldIndex(v.Type(), name)
+		if !ok {
+			return nil, fmt.Errorf("named parameter %q has no field in %s", name, v.Type())
+		}
+		field := fieldByIndex(v, index, false)
+		if !field.IsValid() {
+			// A nil embedded pointer.
+			return nil, nil
+		}
+		return field.Interface(), nil
+	default:
+		return nil, fmt.Errorf("named parameters can't be bound from %T", arg)
+	}
+}
+
+// parseNamed rewrites the named parameters of sql to positional parameters.
+func parseNamed(sql string) *namedQuery {
+	var (
+		b         strings.Builder
+		names     []string
+		positions = make(map[string]int)
+	)
+	b.Grow(len(sql))
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == '\'':
+			end := quotedEnd(sql, i, c, isEscapeString(sql, i))
+			b.WriteString(sql[i:end])
+			i = end
+		case c == '"':
+			end := quotedEnd(sql, i, c, false)
+			b.WriteString(sql[i:end])
+			i = end
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql) - i
+			}
+			b.WriteString(sql[i : i+end])
+			i += end
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := commentEnd(sql, i)
+			b.WriteString(sql[i:end])
+			i = end
+		case c == '$' && dollarTag(sql, i) != "":
+			tag := dollarTag(sql, i)
+			end := strings.Index(sql[i+len(tag):], tag)
+			if end < 0 {
+				end = len(sql)
+			} else {
+				end += i + 2*len(tag)
+			}
+			b.WriteString(sql[i:end])
+			i = end
+		case c == ':' && strings.HasPrefix(sql[i:], "::"):
+			b.WriteString("::")
+			i += 2
+		case (c == ':' || c == '@') && i+1 < len(sql) && isNameStart(sql[i+1]) && !(i > 0 && isNamePart(sql[i-1])) &&
+			!(c == '@' && i > 0 && isOperatorChar(sql[i-1])):
+			end := i + 1
+			for end < len(sql) && isNamePart(sql[end]) {
+				end++
+			}
+			name := sql[i+1 : end]
+			n, ok := positions[name]
+			if !ok {
+				names = append(names, name)
+				n = len(names)
+				positions[name] = n
+			}
+			b.WriteString("$" + strconv.Itoa(n))
+			i = end
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+
+	return &namedQuery{sql: b.String(), names: names}
+}
+
+// quotedEnd returns the end of the string or identifier quoted by quote starting at i.
+// A doubled quote is escaped, and so is any character after a backslash in an escape string.
+func quotedEnd(sql string, i int, quote byte, backslash bool) int {
+	for j := i + 1; j < len(sql); j++ {
+		switch {
+		case backslash && sql[j] == '\\':
+			j++
+		case sql[j] == quote:
+			if j+1 < len(sql) && sql[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(sql)
+}
+
+// isEscapeString reports whether the string starting at i is an E'...' escape string.
+func isEscapeString(sql string, i int) bool {
+	return i > 0 && (sql[i-1] == 'E' || sql[i-1] == 'e') && !(i > 1 && isNamePart(sql[i-2]))
+}
+
+// commentEnd returns the end of the block comment starting at i. Block comments nest.
+func commentEnd(sql string, i int) int {
+	depth := 0
+	for j := i; j < len(sql)-1; j++ {
+		switch {
+		case sql[j] == '/' && sql[j+1] == '*':
+			depth++
+			j++
+		case sql[j] == '*' && sql[j+1] == '/':
+			depth--
+			j++
+			if depth == 0 {
+				return j + 1
+			}
+		}
+	}
+	return len(sql)
+}
+
+// dollarTag returns the $tag$ opening a dollar-quoted string at i, or "" if there is none.
+// Positional parameters such as $1 are not tags.
+func dollarTag(sql string, i int) string {
+	if i > 0 && isNamePart(sql[i-1]) {
+		return ""
+	}
+	for j := i + 1; j < len(sql); j++ {
+		switch {
+		case sql[j] == '$':
+			return sql[i : j+1]
+		case j == i+1 && !isNameStart(sql[j]), !isNamePart(sql[j]):
+			return ""
+		}
+	}
+	return ""
+}
+
+// isOperatorChar reports whether c may be part of an operator, such as @@: an @ following it is not a parameter.
+func isOperatorChar(c byte) bool {
+	return strings.IndexByte("+-*/<>=~!@#%^&|`?", c) >= 0
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c >= 0x80
+}
+
+func isNamePart(c byte) bool {
+	return isNameStart(c) || '0' <= c && c <= '9'
+}
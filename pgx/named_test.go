@@ -0,0 +1,128 @@
+package pgx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgconn"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+// NamedTestSuite defines the structure for the test suite.
+type NamedTestSuite struct {
+	suite.Suite
+}
+
+// TestParseNamed checks the rewrite of the parameters and what is left alone.
+func (t *NamedTestSuite) TestParseNamed() {
+	tests := []struct {
+		sql   string
+		want  string
+		names []string
+	}{
+		{
+			sql:   "INSERT INTO users (id, name) VALUES (:id, @name) ON CONFLICT (id) DO UPDATE SET name = :name",
+			want:  "INSERT INTO users (id, name) VALUES ($1, $2) ON CONFLICT (id) DO UPDATE SET name = $2",
+			names: []string{"id", "name"},
+		},
+		{
+			sql:   "SELECT ':id', 'it''s :id', E'\\' :id', \":id\" FROM t WHERE a = :a",
+			want:  "SELECT ':id', 'it''s :id', E'\\' :id', \":id\" FROM t WHERE a = $1",
+			names: []string{"a"},
+		},
+		{
+			sql:   "SELECT :a::text, arr[1:2], arr[lo:hi] -- :b\nFROM t /* :c /* :d */ :e */ WHERE x = :x",
+			want:  "SELECT $1::text, arr[1:2], arr[lo:hi] -- :b\nFROM t /* :c /* :d */ :e */ WHERE x = $2",
+			names: []string{"a", "x"},
+		},
+		{
+			sql:   "SELECT * FROM docs WHERE tsv @@to_tsquery(:q) OR tsv @@ to_tsquery(@q) OR tags @>@tags",
+			want:  "SELECT * FROM docs WHERE tsv @@to_tsquery($1) OR tsv @@ to_tsquery($1) OR tags @>@tags",
+			names: []string{"q"},
+		},
+		{
+			sql:   "SELECT $$ :a $$, $fn$ @b $fn$, :c",
+			want:  "SELECT $$ :a $$, $fn$ @b $fn$, $1",
+			names: []string{"c"},
+		},
+	}
+
+	for _, test := range tests {
+		q := parseNamed(test.sql)
+		t.Equal(test.want, q.sql)
+		t.Equal(test.names, q.names)
+	}
+}
+
+// TestNamedQueryCache checks that the least recently used query is evicted beyond the size of the cache.
+func (t *NamedTestSuite) TestNamedQueryCache() {
+	cache := newNamedQueryCache(2)
+
+	first := cache.get("SELECT :a")
+	t.Equal("SELECT $1", first.sql)
+	t.Same(first, cache.get("SELECT :a"))
+
+	cache.get("SELECT :b")
+	cache.get("SELECT :a")
+	cache.get("SELECT :c")
+
+	t.Len(cache.items, 2)
+	t.Contains(cache.items, "SELECT :a")
+	t.NotContains(cache.items, "SELECT :b")
+	t.Equal(2, cache.order.Len())
+}
+
+// TestBindNamed checks that the values are taken from a map or a struct.
+func (t *NamedTestSuite) TestBindNamed() {
+	type user struct {
+		copyBase
+		Name string `db:"name"`
+	}
+	const sql = "UPDATE users SET name = :name WHERE id = :id"
+
+	query, args, err := BindNamed(sql, map[string]any{"id": 1, "name": "John"})
+	t.NoError(err)
+	t.Equal("UPDATE users SET name = $1 WHERE id = $2", query)
+	t.Equal([]any{"John", 1}, args)
+
+	_, args, err = BindNamed(sql, &user{copyBase: copyBase{ID: 2}, Name: "Jane"})
+	t.NoError(err)
+	t.Equal([]any{"Jane", int64(2)}, args)
+
+	_, args, err = BindNamed("INSERT INTO posts (title, created_by) VALUES (:title, :created_by)", queryPost{Title: "Hello"})
+	t.NoError(err)
+	t.Equal([]any{"Hello", nil}, args)
+
+	_, _, err = BindNamed(sql, map[string]any{"id": 1})
+	t.EqualError(err, `named parameter "name" has no value`)
+
+	_, _, err = BindNamed(sql, 42)
+	t.EqualError(err, "named parameters can't be bound from int")
+}
+
+// TestNamedExec checks that the statement runs on the transaction of the context.
+func (t *NamedTestSuite) TestNamedExec() {
+	connMock := new(ConnMock)
+	txMock := new(TxMock)
+
+	connMock.On("Begin", mock.Anything).Return(txMock, nil)
+	txMock.On("Exec", mock.Anything, "DELETE FROM users WHERE id = $1", 7).Return(pgconn.CommandTag("DELETE 1"), nil)
+	txMock.On("Commit", mock.Anything).Return(nil)
+
+	transactor := Transactor{conn: connMock}
+
+	err := transactor.WithTx(context.Background(), func(ctx context.Context, tx Tx) error {
+		_, err := NamedExec(ctx, transactor.Querier(ctx), "DELETE FROM users WHERE id = :id", map[string]any{"id": 7})
+		return err
+	})
+
+	t.NoError(err)
+	connMock.AssertExpectations(t.T())
+	txMock.AssertExpectations(t.T())
+}
+
+// TestNamedSuite runs the test suite.
+func TestNamedSuite(t *testing.T) {
+	suite.Run(t, new(NamedTestSuite))
+}
@@ -0,0 +1,12 @@
+package pgx
+
+import "context"
+
+// Notify sends a notification with the payload on the channel with pg_notify. Inside a transaction
+// started by WithTx, the notification is sent on the transaction: PostgreSQL delivers it to the
+// listeners only when the transaction commits, and drops it on rollback, including the rollback
+// to a savepoint it was sent in.
+func (t *Transactor) Notify(ctx context.Context, channel, payload string) error {
+	_, err := t.Querier(ctx).Exec(ctx, "SELECT pg_notify($1, $2)", channel, payload)
+	return err
+}
@@ -0,0 +1,133 @@
+package pgx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	jackcpool "github.com/jackc/pgx/v4/pgxpool"
+)
+
+// Propagation defines how a call relates to the transaction already in the context.
+type Propagation int
+
+const (
+	// PropagationRequired joins the transaction in the context, or starts a new one. It is how WithTx behaves.
+	PropagationRequired Propagation = iota
+	// PropagationRequiresNew suspends the transaction in the context and starts a new one on another
+	// connection of the pool, committed or rolled back on its own. It requires a pool: on a single
+	// connection, the new transaction could not be separated from the suspended one, so it fails
+	// with ErrRequiresNewUnsupported.
+	PropagationRequiresNew
+	// PropagationNested runs in a savepoint of the transaction in the context, or starts a new one.
+	// It is how WithNestedTx behaves.
+	PropagationNested
+	// PropagationMandatory joins the transaction in the context and fails with ErrTxRequired without one.
+	PropagationMandatory
+	// PropagationNever runs without a transaction and fails with ErrTxNotAllowed inside one.
+	PropagationNever
+	// PropagationSupports joins the transaction in the context, or runs without a transaction.
+	PropagationSupports
+)
+
+var (
+	// ErrTxRequired is the error used when PropagationMandatory is requested outside of a transaction.
+	ErrTxRequired = errors.New("transaction required")
+
+	// ErrTxNotAllowed is the error used when PropagationNever is requested inside a transaction.
+	ErrTxNotAllowed = errors.New("transaction not allowed")
+
+	// ErrUnknownPropagation is the error used when an undefined Propagation is requested.
+	ErrUnknownPropagation = errors.New("unknown transaction propagation")
+
+	// ErrRequiresNewUnsupported is the error used when PropagationRequiresNew is requested
+	// from a Transactor whose connection cannot hand out another session, such as a single pgx.Conn.
+	ErrRequiresNewUnsupported = errors.New("PropagationRequiresNew requires a connection pool")
+)
+
+// acquirer is implemented by the connections that hand out separate sessions, such as *pgxpool.Pool.
+type acquirer interface {
+	Acquire(ctx context.Context) (*jackcpool.Conn, error)
+}
+
+func (p Propagation) String() string {
+	switch p {
+	case PropagationRequired:
+		return "REQUIRED"
+	case PropagationRequiresNew:
+		return "REQUIRES_NEW"
+	case PropagationNested:
+		return "NESTED"
+	case PropagationMandatory:
+		return "MANDATORY"
+	case PropagationNever:
+		return "NEVER"
+	case PropagationSupports:
+		return "SUPPORTS"
+	default:
+		return fmt.Sprintf("Propagation(%d)", int(p))
+	}
+}
+
+// WithPropagation executes a function according to the given propagation mode.
+// When the function runs without a transaction, under PropagationNever or PropagationSupports,
+// it receives a nil Tx; its queries go through Querier, which then returns the connection pool.
+func (t *Transactor) WithPropagation(ctx context.Context, propagation Propagation, tFunc func(context.Context, Tx) error) error {
+	_, inTx := ctx.Value(t.txKey()).(Tx)
+
+	switch propagation {
+	case PropagationRequired:
+		return t.WithTx(ctx, tFunc)
+	case PropagationRequiresNew:
+		if _, ok := t.conn.(acquirer); !ok {
+			return ErrRequiresNewUnsupported
+		}
+		return t.WithTx(t.suspend(ctx), tFunc)
+	case PropagationNested:
+		return t.WithNestedTx(ctx, tFunc)
+	case PropagationMandatory:
+		if !inTx {
+			return ErrTxRequired
+		}
+		return t.WithTx(ctx, tFunc)
+	case PropagationNever:
+		if inTx {
+			return ErrTxNotAllowed
+		}
+		return tFunc(ctx, nil)
+	case PropagationSupports:
+		if inTx {
+			return t.WithTx(ctx, tFunc)
+		}
+		return tFunc(ctx, nil)
+	default:
+		return fmt.Errorf("%w: %v", ErrUnknownPropagation, propagation)
+	}
+}
+
+// suspend returns a copy of ctx in which the transaction of the Transactor is hidden,
+// along with its state, so that a new transaction starts from scratch. The hooks, unit of work,
+// counter and retry attempt of the suspended transaction are hidden as well.
+func (t *Transactor) suspend(ctx context.Context) context.Context {
+	for _, key := range []any{
+		t.txKey(),
+		t.txOptionsKey(),
+		t.txReadOnlyKey(),
+		t.txTraceKey(),
+		txInfoKey{conn: t.conn},
+		txInfoKey{},
+		txHooksKey{scope: t.conn},
+		txHooksKey{},
+		txUnitKey{conn: t.conn},
+		txUnitKey{},
+		txCounterKey{conn: t.conn},
+		txAttemptKey{conn: t.conn},
+		txAttemptKey{},
+	} {
+		if ctx.Value(key) != nil {
+			ctx = context.WithValue(ctx, key, nil)
+		}
+	}
+
+	return ctx
+}
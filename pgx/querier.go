@@ -0,0 +1,27 @@
+package pgx
+
+import (
+	"context"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// Querier defines the interface for executing queries, shared by transactions and connection pools.
+type Querier interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
+// Querier returns the transaction stored in the context, or the master connection pool
+// when there is none. Repositories that run their queries through it take part in the
+// ambient transaction started by WithTx without having to receive it explicitly.
+func (t *Transactor) Querier(ctx context.Context) Querier {
+	if tx, ok := ctx.Value(t.txKey()).(Tx); ok {
+		return tx
+	}
+	return t.conn
+}
@@ -0,0 +1,139 @@
+package pgx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/jackc/pgproto3/v2"
+	"github.com/jackc/pgx/v4"
+)
+
+// ErrNotFound is the error returned by QueryOne when the query returns no rows.
+// It wraps pgx.ErrNoRows too.
+var ErrNotFound = errors.New("not found")
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+)
+
+// QueryOne runs the query on q and scans its first row into a T. Run it on the Querier
+// of a TxManager to take part in the transaction of the context.
+//
+// A struct T is scanned field by field: the columns are mapped to the fields by their db tag,
+// or by their lower-cased name without one, and the fields of embedded structs are promoted.
+// A column matching no name exactly is matched case-insensitively, and a column without
+// a field is an error. NULL values are scanned into pointer fields. Any other T, such as
+// a time.Time or a sql.Scanner, is scanned from the single column of the query.
+//
+// It returns ErrNotFound when the query returns no rows.
+func QueryOne[T any](ctx context.Context, q Querier, sql string, args ...any) (T, error) {
+	var (
+		one   T
+		found bool
+	)
+	err := queryRows(ctx, q, sql, args, func(v T) bool {
+		one, found = v, true
+		return false
+	})
+	if err == nil && !found {
+		err = fmt.Errorf("%w: %w", ErrNotFound, pgx.ErrNoRows)
+	}
+
+	return one, err
+}
+
+// QueryAll runs the query on q and scans all its rows into a slice of T, mapped like QueryOne.
+// It returns an empty slice when the query returns no rows.
+func QueryAll[T any](ctx context.Context, q Querier, sql string, args ...any) ([]T, error) {
+	all := []T{}
+	err := queryRows(ctx, q, sql, args, func(v T) bool {
+		all = append(all, v)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return all, nil
+}
+
+// QueryMap runs the query on q and scans all its rows into a map of T by the key returned by key,
+// mapped like QueryOne. Of the rows with the same key, the last one is kept.
+func QueryMap[K comparable, T any](ctx context.Context, q Querier, key func(T) K, sql string, args ...any) (map[K]T, error) {
+	all := make(map[K]T)
+	err := queryRows(ctx, q, sql, args, func(v T) bool {
+		all[key(v)] = v
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return all, nil
+}
+
+// queryRows runs the query and calls fn with each row scanned into a T until it returns false.
+func queryRows[T any](ctx context.Context, q Querier, sql string, args []any, fn func(T) bool) error {
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var dest func(*T) []any
+	for rows.Next() {
+		if dest == nil {
+			if dest, err = scanDest[T](rows.FieldDescriptions()); err != nil {
+				return err
+			}
+		}
+
+		var v T
+		if err = rows.Scan(dest(&v)...); err != nil {
+			return err
+		}
+		if !fn(v) {
+			break
+		}
+	}
+	rows.Close()
+
+	return rows.Err()
+}
+
+// scanDest returns the function returning the scan destinations of the columns in a T.
+func scanDest[T any](columns []pgproto3.FieldDescription) (func(*T) []any, error) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+
+	if typ.Kind() != reflect.Struct || typ == timeType || reflect.PointerTo(typ).Implements(scannerType) {
+		if len(columns) != 1 {
+			return nil, fmt.Errorf("can't scan %d columns into %s", len(columns), typ)
+		}
+		return func(v *T) []any {
+			return []any{v}
+		}, nil
+	}
+
+	indexes := make([][]int, len(columns))
+	for i, column := range columns {
+		index, ok := fieldIndex(typ, string(column.Name))
+		if !ok {
+			return nil, fmt.Errorf("column %q has no field in %s", column.Name, typ)
+		}
+		indexes[i] = index
+	}
+
+	return func(v *T) []any {
+		s := reflect.ValueOf(v).Elem()
+		dest := make([]any, len(indexes))
+		for i, index := range indexes {
+			dest[i] = fieldByIndex(s, index, true).Addr().Interface()
+		}
+		return dest
+	}, nil
+}
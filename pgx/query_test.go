@@ -0,0 +1,166 @@
+package pgx
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgproto3/v2"
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+// valueRows returns the given values as rows of the columns.
+type valueRows struct {
+	pgx.Rows
+	columns []string
+	values  [][]any
+	next    int
+	closed  bool
+}
+
+func (r *valueRows) FieldDescriptions() []pgproto3.FieldDescription {
+	fields := make([]pgproto3.FieldDescription, len(r.columns))
+	for i, column := range r.columns {
+		fields[i].Name = []byte(column)
+	}
+	return fields
+}
+
+func (r *valueRows) Next() bool {
+	r.next++
+	return !r.closed && r.next <= len(r.values)
+}
+
+func (r *valueRows) Scan(dest ...interface{}) error {
+	for i, value := range r.values[r.next-1] {
+		d := reflect.ValueOf(dest[i]).Elem()
+		if value == nil {
+			d.Set(reflect.Zero(d.Type()))
+			continue
+		}
+		v := reflect.ValueOf(value)
+		if d.Kind() == reflect.Pointer {
+			d.Set(reflect.New(d.Type().Elem()))
+			d = d.Elem()
+		}
+		d.Set(v)
+	}
+	return nil
+}
+
+func (r *valueRows) Err() error { return nil }
+
+func (r *valueRows) Close() { r.closed = true }
+
+type queryBase struct {
+	ID int64 `db:"id"`
+}
+
+type queryUser struct {
+	queryBase
+	Name     string  `db:"name"`
+	Email    *string `db:"email"`
+	LastName string  `db:"Last_Name"`
+}
+
+// QueryAudit is embedded by pointer, which requires an exported type.
+type QueryAudit struct {
+	CreatedBy string `db:"created_by"`
+}
+
+type queryPost struct {
+	*QueryAudit
+	Title string `db:"title"`
+}
+
+// QueryTestSuite defines the structure for the test suite.
+type QueryTestSuite struct {
+	suite.Suite
+}
+
+func (t *QueryTestSuite) querier(columns []string, values ...[]any) *QuerierMock {
+	q := new(QuerierMock)
+	q.On("Query", mock.Anything, "SELECT", mock.Anything).Return(&valueRows{columns: columns, values: values}, nil)
+	return q
+}
+
+// TestQueryOne checks that the columns are mapped to the fields, including the promoted
+// and the case-insensitive ones, and that NULL is scanned into a pointer.
+func (t *QueryTestSuite) TestQueryOne() {
+	q := t.querier([]string{"id", "NAME", "email", "last_name"},
+		[]any{int64(1), "John", nil, "Doe"},
+		[]any{int64(2), "Jane", "jane@example.com", "Doe"},
+	)
+
+	user, err := QueryOne[queryUser](context.Background(), q, "SELECT")
+
+	t.NoError(err)
+	t.Equal(queryUser{queryBase: queryBase{ID: 1}, Name: "John", LastName: "Doe"}, user)
+}
+
+// TestQueryOneNotFound checks that no rows is ErrNotFound, which wraps pgx.ErrNoRows.
+func (t *QueryTestSuite) TestQueryOneNotFound() {
+	_, err := QueryOne[queryUser](context.Background(), t.querier([]string{"id"}), "SELECT")
+
+	t.ErrorIs(err, ErrNotFound)
+	t.ErrorIs(err, pgx.ErrNoRows)
+}
+
+// TestQueryAll checks the scan of all the rows, of a struct and of a single column.
+func (t *QueryTestSuite) TestQueryAll() {
+	email := "jane@example.com"
+	q := t.querier([]string{"id", "name", "email"},
+		[]any{int64(1), "John", nil},
+		[]any{int64(2), "Jane", email},
+	)
+
+	users, err := QueryAll[queryUser](context.Background(), q, "SELECT")
+
+	t.NoError(err)
+	t.Equal([]queryUser{
+		{queryBase: queryBase{ID: 1}, Name: "John"},
+		{queryBase: queryBase{ID: 2}, Name: "Jane", Email: &email},
+	}, users)
+
+	names, err := QueryAll[string](context.Background(), t.querier([]string{"name"}, []any{"John"}), "SELECT")
+
+	t.NoError(err)
+	t.Equal([]string{"John"}, names)
+}
+
+// TestQueryMap checks that the rows are keyed by the key function.
+func (t *QueryTestSuite) TestQueryMap() {
+	q := t.querier([]string{"id", "name"}, []any{int64(1), "John"}, []any{int64(2), "Jane"})
+
+	users, err := QueryMap(context.Background(), q, func(u queryUser) int64 { return u.ID }, "SELECT")
+
+	t.NoError(err)
+	t.Equal(map[int64]queryUser{
+		1: {queryBase: queryBase{ID: 1}, Name: "John"},
+		2: {queryBase: queryBase{ID: 2}, Name: "Jane"},
+	}, users)
+}
+
+// TestQueryEmbeddedPointer checks that the embedded pointers are allocated to scan their fields.
+func (t *QueryTestSuite) TestQueryEmbeddedPointer() {
+	q := t.querier([]string{"title", "created_by"}, []any{"Hello", "john"})
+
+	post, err := QueryOne[queryPost](context.Background(), q, "SELECT")
+
+	t.NoError(err)
+	t.Equal(queryPost{QueryAudit: &QueryAudit{CreatedBy: "john"}, Title: "Hello"}, post)
+}
+
+// TestUnknownColumn checks that a column without a field is an error.
+func (t *QueryTestSuite) TestUnknownColumn() {
+	_, err := QueryAll[queryUser](context.Background(), t.querier([]string{"age"}, []any{42}), "SELECT")
+
+	t.EqualError(err, `column "age" has no field in pgx.queryUser`)
+}
+
+// TestQuerySuite runs the test suite.
+func TestQuerySuite(t *testing.T) {
+	suite.Run(t, new(QueryTestSuite))
+}
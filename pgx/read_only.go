@@ -0,0 +1,54 @@
+package pgx
+
+import (
+	"context"
+	"errors"
+)
+
+// txContextReadOnlyKey marks the context of a transaction started by WithReadOnlyTx.
+type txContextReadOnlyKey struct {
+	conn Conn
+}
+
+var (
+	// ErrNoReplica is the error used when a read only transaction is requested,
+	// there is no slave pool and the fallback to master is disabled.
+	ErrNoReplica = errors.New("no replica available for read only transaction")
+
+	// ErrReadOnlyTx is the error used when a read write transaction is nested
+	// inside a transaction started by WithReadOnlyTx.
+	ErrReadOnlyTx = errors.New("read write transaction requested inside a read only transaction")
+)
+
+// WithReplicaFallback is an option to control whether read only transactions
+// run on the master pool when there is no slave pool (default: true).
+func WithReplicaFallback(enabled bool) Option {
+	return func(t *Transactor) {
+		t.replicaFallback = enabled
+	}
+}
+
+// readConn returns the connection to start a read only transaction on, with the index of its node.
+func (t *Transactor) readConn() (Conn, int, error) {
+	if t.replica != nil {
+		conn, node := t.replica()
+		return conn, node, nil
+	}
+	if !t.replicaFallback {
+		return nil, 0, ErrNoReplica
+	}
+	return t.conn, 0, nil
+}
+
+// txReadOnlyKey returns the key marking the context of a transaction started by WithReadOnlyTx.
+func (t *Transactor) txReadOnlyKey() txContextReadOnlyKey {
+	return txContextReadOnlyKey{conn: t.conn}
+}
+
+// checkReadOnly rejects joining a transaction started by WithReadOnlyTx unless opts request read only access.
+func (t *Transactor) checkReadOnly(ctx context.Context, opts TxOptions) error {
+	if readOnly, _ := ctx.Value(t.txReadOnlyKey()).(bool); readOnly && opts.AccessMode != ReadOnly {
+		return ErrReadOnlyTx
+	}
+	return nil
+}
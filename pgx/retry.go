@@ -0,0 +1,153 @@
+package pgx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/jackc/pgconn"
+)
+
+const (
+	// SQLSTATE codes of errors that are resolved by re-running the transaction.
+	codeSerializationFailure = "40001"
+	codeDeadlockDetected     = "40P01"
+
+	defaultMaxAttempts    = 3
+	defaultInitialBackoff = 10 * time.Millisecond
+	defaultMaxBackoff     = time.Second
+)
+
+// RetryPolicy configures the re-execution of transactions that failed with a retryable error.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one (default: 3).
+	MaxAttempts int
+	// InitialBackoff is the delay before the second attempt (default: 10ms).
+	// It doubles with each next attempt.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts (default: 1s).
+	MaxBackoff time.Duration
+	// Retryable reports whether the error is worth re-running the transaction (default: IsRetryable).
+	Retryable func(error) bool
+	// OnRetry is called before each retry with the number of the failed attempt and its error.
+	OnRetry func(ctx context.Context, attempt int, err error)
+}
+
+// RetryError is returned when a transaction still fails after it has been retried.
+type RetryError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("transaction failed after %d attempts: %v", e.Attempts, e.Err)
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// txAttemptKey is used for storing the number of the current attempt in the context.
+// It is namespaced by the connection of the Transactor, the zero key holding the attempt
+// of the innermost retried transaction of any Transactor.
+type txAttemptKey struct {
+	conn Conn
+}
+
+// WithRetryPolicy is an option to re-run failed transactions according to the given policy.
+// Retries happen only at the outermost level: a nested call never restarts the transaction on its own.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(t *Transactor) {
+		SetDefaultRetryValues(&policy)
+		t.retry = &policy
+	}
+}
+
+// SetDefaultRetryValues sets default values for RetryPolicy if they are not specified.
+func SetDefaultRetryValues(policy *RetryPolicy) {
+	if policy.MaxAttempts == 0 {
+		policy.MaxAttempts = defaultMaxAttempts
+	}
+	if policy.InitialBackoff == 0 {
+		policy.InitialBackoff = defaultInitialBackoff
+	}
+	if policy.MaxBackoff == 0 {
+		policy.MaxBackoff = defaultMaxBackoff
+	}
+	if policy.Retryable == nil {
+		policy.Retryable = IsRetryable
+	}
+}
+
+// IsRetryable reports whether err is a serialization failure or a deadlock.
+func IsRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == codeSerializationFailure || pgErr.Code == codeDeadlockDetected
+}
+
+// RetryAttempt returns the number of the current attempt of the outermost transaction, starting from 1.
+func RetryAttempt(ctx context.Context) int {
+	if attempt, ok := ctx.Value(txAttemptKey{}).(int); ok {
+		return attempt
+	}
+	return 1
+}
+
+// withRetry runs fn and re-runs it while it fails with a retryable error and attempts remain.
+// The error of an attempt made after a retry is wrapped into RetryError.
+func (t *Transactor) withRetry(ctx context.Context, fn func(context.Context) error) error {
+	if t.retry == nil {
+		return fn(ctx)
+	}
+
+	for attempt := 1; ; attempt++ {
+		attemptCtx := context.WithValue(ctx, txAttemptKey{conn: t.conn}, attempt)
+		err := fn(context.WithValue(attemptCtx, txAttemptKey{}, attempt))
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= t.retry.MaxAttempts || !t.retry.Retryable(err) {
+			if attempt > 1 {
+				return &RetryError{Attempts: attempt, Err: err}
+			}
+			return err
+		}
+
+		t.countTx(metricTxRetried)
+		if t.retry.OnRetry != nil {
+			t.retry.OnRetry(ctx, attempt, err)
+		}
+
+		timer := time.NewTimer(t.retry.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return &RetryError{Attempts: attempt, Err: errors.Join(err, ctx.Err())}
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns the delay after the given attempt: an exponentially growing
+// duration capped by MaxBackoff, of which the upper half is randomized.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.InitialBackoff
+	for i := 1; i < attempt && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
@@ -0,0 +1,106 @@
+package pgx
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// structField maps a column to a field of a struct.
+type structField struct {
+	column string
+	index  []int
+}
+
+// structMappings caches the fields of the struct types by reflect.Type.
+var structMappings sync.Map
+
+// structFields returns the columns of a struct type: the exported fields named by their db tag,
+// or by their lower-cased name without one. Fields tagged with db:"-" are skipped, and the fields
+// of embedded structs are promoted, unless the embedded struct is tagged itself. So are those of
+// an embedded pointer to an exported struct type, which is allocated on scan, unless the type is
+// already being mapped: a struct reaching itself through pointers would be mapped forever.
+func structFields(typ reflect.Type) []structField {
+	if fields, ok := structMappings.Load(typ); ok {
+		return fields.([]structField)
+	}
+
+	fields := appendStructFields(nil, typ, nil, map[reflect.Type]bool{typ: true})
+	structMappings.Store(typ, fields)
+
+	return fields
+}
+
+func appendStructFields(fields []structField, typ reflect.Type, index []int, visiting map[reflect.Type]bool) []structField {
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		tag, tagged := f.Tag.Lookup("db")
+		if tag == "-" {
+			continue
+		}
+
+		fieldIndex := make([]int, len(index)+1)
+		copy(fieldIndex, index)
+		fieldIndex[len(index)] = i
+
+		if f.Anonymous && !tagged && f.Type.Kind() == reflect.Struct {
+			fields = appendStructFields(fields, f.Type, fieldIndex, visiting)
+			continue
+		}
+		if f.Anonymous && !tagged && f.Type.Kind() == reflect.Pointer && f.Type.Elem().Kind() == reflect.Struct {
+			if f.IsExported() && !visiting[f.Type.Elem()] {
+				visiting[f.Type.Elem()] = true
+				fields = appendStructFields(fields, f.Type.Elem(), fieldIndex, visiting)
+				delete(visiting, f.Type.Elem())
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		column := tag
+		if column == "" {
+			column = strings.ToLower(f.Name)
+		}
+		fields = append(fields, structField{column: column, index: fieldIndex})
+	}
+
+	return fields
+}
+
+// fieldIndex returns the index of the field of the struct type mapped to column.
+// The column names are matched exactly first, then case-insensitively.
+func fieldIndex(typ reflect.Type, column string) ([]int, bool) {
+	fields := structFields(typ)
+	for _, f := range fields {
+		if f.column == column {
+			return f.index, true
+		}
+	}
+	for _, f := range fields {
+		if strings.EqualFold(f.column, column) {
+			return f.index, true
+		}
+	}
+
+	return nil, false
+}
+
+// fieldByIndex returns the field of the struct v at index. The nil embedded pointers on the way
+// are allocated when alloc is set; otherwise, an invalid value is returned through them.
+func fieldByIndex(v reflect.Value, index []int, alloc bool) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !alloc {
+					return reflect.Value{}
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
@@ -0,0 +1,93 @@
+package pgx
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Timeouts are the limits applied with SET LOCAL to every new transaction.
+// Zero values leave the settings of the session unchanged.
+type Timeouts struct {
+	// Statement aborts any statement that takes longer (statement_timeout).
+	Statement time.Duration
+	// Lock aborts any statement that waits longer for a lock (lock_timeout).
+	Lock time.Duration
+	// IdleInTransaction terminates the session when the transaction stays idle
+	// for longer (idle_in_transaction_session_timeout).
+	IdleInTransaction time.Duration
+}
+
+type txContextTimeoutsKey struct{}
+
+// txTimeoutsKey is used for storing the per call timeouts in the context.
+var txTimeoutsKey = txContextTimeoutsKey{}
+
+// WithTimeouts is an option to apply the given timeouts to every transaction started by the Transactor.
+func WithTimeouts(timeouts Timeouts) Option {
+	return func(t *Transactor) {
+		t.timeouts = timeouts
+	}
+}
+
+// ContextWithTimeouts returns a copy of ctx whose transactions use the given timeouts.
+// Its non-zero values override the timeouts configured on the Transactor.
+// Only a new transaction is affected: a call joining an ongoing transaction keeps its limits.
+func ContextWithTimeouts(ctx context.Context, timeouts Timeouts) context.Context {
+	return context.WithValue(ctx, txTimeoutsKey, timeouts)
+}
+
+// merge returns t with the non-zero values of override applied.
+func (t Timeouts) merge(override Timeouts) Timeouts {
+	if override.Statement != 0 {
+		t.Statement = override.Statement
+	}
+	if override.Lock != 0 {
+		t.Lock = override.Lock
+	}
+	if override.IdleInTransaction != 0 {
+		t.IdleInTransaction = override.IdleInTransaction
+	}
+	return t
+}
+
+// sql returns the SET LOCAL statements applying the timeouts, or an empty string if there are none.
+func (t Timeouts) sql() string {
+	var stmts []string
+	for _, s := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"statement_timeout", t.Statement},
+		{"lock_timeout", t.Lock},
+		{"idle_in_transaction_session_timeout", t.IdleInTransaction},
+	} {
+		if s.value > 0 {
+			stmts = append(stmts, fmt.Sprintf("SET LOCAL %s = %d", s.name, milliseconds(s.value)))
+		}
+	}
+	return strings.Join(stmts, "; ")
+}
+
+// milliseconds returns d in milliseconds, rounded up: a sub-millisecond timeout
+// must not turn into 0, which disables it.
+func milliseconds(d time.Duration) int64 {
+	return int64((d + time.Millisecond - 1) / time.Millisecond)
+}
+
+// setTimeouts applies the timeouts of the Transactor, overridden by those in the context, to tx.
+func (t *Transactor) setTimeouts(ctx context.Context, tx Tx) error {
+	timeouts := t.timeouts
+	if override, ok := ctx.Value(txTimeoutsKey).(Timeouts); ok {
+		timeouts = timeouts.merge(override)
+	}
+
+	sql := timeouts.sql()
+	if sql == "" {
+		return nil
+	}
+
+	_, err := tx.Exec(ctx, sql)
+	return err
+}
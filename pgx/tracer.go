@@ -0,0 +1,96 @@
+package pgx
+
+import (
+	"context"
+	"time"
+
+	"github.com/i4erkasov/go-pgsql/pgxpool"
+)
+
+// Tracer is an alias to pgxpool.Tracer
+type Tracer = pgxpool.Tracer
+
+// txTrace holds the tracing state of a transaction or savepoint.
+type txTrace struct {
+	start time.Time
+	node  int
+	depth int
+}
+
+// txContextTraceKey is used for storing the tracing state of the active transaction in the context.
+type txContextTraceKey struct {
+	conn Conn
+}
+
+// WithTracer is an option to report the begin, commit and rollback of transactions
+// and savepoints to the tracer. Pass the same tracer to pgxpool.WithTracer to trace
+// the queries executed on the pools.
+func WithTracer(tracer Tracer) Option {
+	return func(t *Transactor) {
+		t.tracer = tracer
+	}
+}
+
+// txTraceKey returns the key the tracing state of the active transaction is stored under in the context.
+func (t *Transactor) txTraceKey() txContextTraceKey {
+	return txContextTraceKey{conn: t.conn}
+}
+
+// traceTxStart reports the start of a transaction on the given node, or of a savepoint
+// when a transaction is already traced in the context, to the tracer and the metrics.
+func (t *Transactor) traceTxStart(ctx context.Context, node int, opts TxOptions) context.Context {
+	if t.tracer == nil && t.metrics == nil {
+		return ctx
+	}
+
+	trace := &txTrace{start: time.Now(), node: node, depth: 1}
+	if parent, ok := ctx.Value(t.txTraceKey()).(*txTrace); ok {
+		trace.node = parent.node
+		trace.depth = parent.depth + 1
+	}
+
+	if trace.depth == 1 {
+		t.countTx(metricTxBegun)
+	}
+
+	if t.tracer != nil {
+		ctx = t.tracer.TraceTxStart(ctx, pgxpool.TraceTxStartData{
+			Pool:      t.name,
+			Node:      trace.node,
+			Depth:     trace.depth,
+			Options:   opts,
+			StartTime: trace.start,
+		})
+	}
+
+	return context.WithValue(ctx, t.txTraceKey(), trace)
+}
+
+// traceTxEnd reports the end of the transaction or savepoint traced in the context
+// to the tracer and the metrics.
+func (t *Transactor) traceTxEnd(ctx context.Context, committed bool, err error) {
+	if t.tracer == nil && t.metrics == nil {
+		return
+	}
+
+	trace, ok := ctx.Value(t.txTraceKey()).(*txTrace)
+	if !ok {
+		return
+	}
+
+	duration := time.Since(trace.start)
+	if trace.depth == 1 {
+		t.observeTxEnd(committed, duration)
+	}
+
+	if t.tracer != nil {
+		t.tracer.TraceTxEnd(ctx, pgxpool.TraceTxEndData{
+			Pool:      t.name,
+			Node:      trace.node,
+			Depth:     trace.depth,
+			Committed: committed,
+			Duration:  duration,
+			Err:       err,
+		})
+	}
+}
@@ -0,0 +1,459 @@
+package pgx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/i4erkasov/go-pgsql/pgxpool"
+)
+
+const (
+	defaultGIDPrefix = "pgsql2pc"
+	// defaultAbortRetention is how long Recover keeps the decisions to abort.
+	defaultAbortRetention = 7 * 24 * time.Hour
+	// decisionLogTable records the decisions of the Coordinator and of Recover.
+	decisionLogTable = "tx_decision_log"
+
+	decisionCommit = "commit"
+	decisionAbort  = "abort"
+)
+
+var (
+	// ErrInDoubt is the error used when a distributed transaction could not be completed on every
+	// participant after its outcome was decided. The prepared transactions left behind are resolved
+	// by Coordinator.Recover according to the decision log.
+	ErrInDoubt = errors.New("distributed transaction in doubt")
+
+	// ErrNoParticipants is the error used when a distributed transaction is started without pools.
+	ErrNoParticipants = errors.New("no participants in distributed transaction")
+
+	// ErrPresumedAbort is the error used when Recover decided to abort a distributed transaction
+	// before its Coordinator could record the decision to commit it.
+	ErrPresumedAbort = errors.New("distributed transaction aborted by recovery")
+)
+
+// Coordinator runs distributed transactions over several named pools of a Registry with two-phase commit.
+// Its decisions are recorded in the tx_decision_log table of the decision log pool,
+// created with CreateDecisionLog. The servers must allow prepared transactions (max_prepared_transactions > 0).
+type Coordinator struct {
+	conns     map[string]Conn
+	logPool   string
+	gidPrefix string
+	// abortRetention is how long the decisions to abort are kept in the log.
+	abortRetention time.Duration
+}
+
+// CoordinatorOption defines the type for functional options for Coordinator.
+type CoordinatorOption func(*Coordinator)
+
+// WithDecisionLogPool is an option to record the decisions in the pool with the given name (default: pgxpool.DEFAULT).
+func WithDecisionLogPool(name string) CoordinatorOption {
+	return func(c *Coordinator) {
+		c.logPool = name
+	}
+}
+
+// WithGIDPrefix is an option to set the prefix of the identifiers of the prepared transactions (default: "pgsql2pc").
+// Coordinators of different applications sharing a server must use different prefixes.
+func WithGIDPrefix(prefix string) CoordinatorOption {
+	return func(c *Coordinator) {
+		c.gidPrefix = prefix
+	}
+}
+
+// WithAbortRetention is an option to set how long the decisions to abort claimed by Recover are kept
+// in the log (default: 7 days). While it is kept, the Coordinator of an aborted transaction cannot commit it:
+// the retention must be far longer than a Coordinator may stall between the phases. A zero retention keeps them forever.
+func WithAbortRetention(retention time.Duration) CoordinatorOption {
+	return func(c *Coordinator) {
+		c.abortRetention = retention
+	}
+}
+
+// PreparedTx is a prepared transaction, as listed in pg_prepared_xacts.
+type PreparedTx struct {
+	// Pool is the name of the pool the transaction was found on.
+	Pool     string
+	GID      string
+	Prepared time.Time
+	Owner    string
+	Database string
+	// Managed reports whether the transaction was prepared by a Coordinator with the same prefix.
+	Managed bool
+}
+
+// participant is a pool taking part in a distributed transaction.
+type participant struct {
+	name string
+	conn Conn
+	tx   Tx
+	gid  string
+}
+
+// NewCoordinator creates a Coordinator for the pools of the registry. The transactions run on the master nodes.
+func NewCoordinator(registry *pgxpool.Registry, opts ...CoordinatorOption) (*Coordinator, error) {
+	conns := make(map[string]Conn)
+	for _, name := range registry.Names() {
+		pools, err := registry.GetPoolName(name)
+		if err != nil {
+			return nil, err
+		}
+		conns[name] = pools.Master()
+	}
+
+	c := newCoordinator(conns, opts)
+	if _, ok := c.conns[c.logPool]; !ok {
+		return nil, fmt.Errorf("decision log pool %q: %w", c.logPool, pgxpool.ErrUnknownPool)
+	}
+
+	return c, nil
+}
+
+// newCoordinator creates a Coordinator with conns and applies the options to it.
+func newCoordinator(conns map[string]Conn, opts []CoordinatorOption) *Coordinator {
+	c := &Coordinator{
+		conns:          conns,
+		logPool:        pgxpool.DEFAULT,
+		gidPrefix:      defaultGIDPrefix,
+		abortRetention: defaultAbortRetention,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// CreateDecisionLog creates the decision log table if it does not exist.
+func (c *Coordinator) CreateDecisionLog(ctx context.Context) error {
+	_, err := c.conns[c.logPool].Exec(ctx, "CREATE TABLE IF NOT EXISTS "+decisionLogTable+` (
+    id           text PRIMARY KEY,
+    participants text[]      NOT NULL,
+    decision     text        NOT NULL CHECK (decision IN ('commit', 'abort')),
+    decided_at   timestamptz NOT NULL DEFAULT now()
+)`)
+	return err
+}
+
+// WithDistributedTx executes a function within transactions started on each of the named pools.
+// The transactions are passed to the function by pool name and are stored in the context,
+// so that a TxManager created for one of the pools joins its transaction.
+// If the function succeeds, the transactions are prepared, the decision to commit is recorded
+// and they are committed; otherwise, they are all rolled back. When the outcome is decided but
+// could not be applied everywhere, an error wrapping ErrInDoubt is returned and Recover completes it.
+// Commit and rollback hooks registered in the context, including in the savepoints of the participants,
+// run once the whole transaction has ended.
+func (c *Coordinator) WithDistributedTx(ctx context.Context, names []string, tFunc func(context.Context, map[string]Tx) error) (err error) {
+	if len(names) == 0 {
+		return ErrNoParticipants
+	}
+
+	id, err := newTxID()
+	if err != nil {
+		return err
+	}
+
+	parent := ctx
+	parts := make([]*participant, 0, len(names))
+	txs := make(map[string]Tx, len(names))
+	for _, name := range names {
+		conn, ok := c.conns[name]
+		if !ok {
+			c.rollback(ctx, parts)
+			return fmt.Errorf("pool %q: %w", name, pgxpool.ErrUnknownPool)
+		}
+
+		tx, beginErr := conn.Begin(ctx)
+		if beginErr != nil {
+			c.rollback(ctx, parts)
+			return beginErr
+		}
+
+		parts = append(parts, &participant{name: name, conn: conn, tx: tx, gid: c.gid(id, name)})
+		txs[name] = tx
+		ctx = context.WithValue(ctx, txContextKey{conn: conn}, tx)
+		ctx = (&Transactor{conn: conn}).withTxInfo(ctx, tx)
+	}
+	// The hooks registered by the participants are scoped to the coordinator and run once the outcome is known.
+	// They are stored for the connection of each participant too, so that the savepoints started by its
+	// TxManager hand their hooks over to the distributed transaction.
+	ctx, hooks := withTxHooks(ctx, c)
+	for _, part := range parts {
+		ctx = context.WithValue(ctx, txHooksKey{scope: part.conn}, hooks)
+	}
+	runner := &Transactor{}
+	defer func() {
+		if p := recover(); p != nil {
+			c.rollback(ctx, parts)
+			runner.afterRollback(parent, hooks, fmt.Errorf("panic in transaction: %v", p))
+			panic(p)
+		}
+		if err != nil && !errors.Is(err, ErrInDoubt) {
+			runner.afterRollback(parent, hooks, err)
+		}
+	}()
+
+	if err = tFunc(ctx, txs); err != nil {
+		c.rollback(ctx, parts)
+		return err
+	}
+
+	if err = c.prepare(ctx, parts); err != nil {
+		return err
+	}
+
+	if err = c.decide(ctx, id, parts); err != nil {
+		return err
+	}
+
+	if err = c.commitPrepared(ctx, id, parts); err != nil {
+		return err
+	}
+
+	runner.afterCommit(parent, hooks)
+	return nil
+}
+
+// prepare runs the first phase: it prepares the transaction of every participant.
+// If any of them fails, the transactions prepared so far are rolled back along with the others.
+func (c *Coordinator) prepare(ctx context.Context, parts []*participant) error {
+	for i, part := range parts {
+		if _, err := part.tx.Exec(ctx, "PREPARE TRANSACTION "+quoteLiteral(part.gid)); err != nil {
+			c.rollbackPrepared(ctx, parts[:i])
+			c.rollback(ctx, parts[i:])
+			return fmt.Errorf("failed to prepare transaction on pool %q: %w", part.name, err)
+		}
+
+		// The session has left the transaction block; ending the pgx transaction only releases the connection.
+		_ = part.tx.Rollback(ctx)
+	}
+
+	return nil
+}
+
+// decide durably records the decision to commit. The decision is claimed atomically against Recover,
+// which claims the abort of the transactions it finds without a decision: if Recover won, the
+// transaction is rolled back. Without a record, the transaction is presumed aborted.
+func (c *Coordinator) decide(ctx context.Context, id string, parts []*participant) error {
+	names := make([]string, len(parts))
+	for i, part := range parts {
+		names[i] = part.name
+	}
+
+	claimed, err := c.claimDecision(ctx, id, names, decisionCommit)
+	if err == nil && !claimed {
+		c.rollbackPrepared(ctx, parts)
+		return ErrPresumedAbort
+	}
+	if err == nil {
+		return nil
+	}
+
+	// The record might have been written even though an error was returned. Remove it before aborting,
+	// or leave the outcome to Recover if that is not possible.
+	detached := context.WithoutCancel(ctx)
+	_, delErr := c.conns[c.logPool].Exec(detached,
+		"DELETE FROM "+decisionLogTable+" WHERE id = $1 AND decision = '"+decisionCommit+"'", id)
+	if delErr != nil {
+		return fmt.Errorf("%w: failed to record decision: %w", ErrInDoubt, errors.Join(err, delErr))
+	}
+
+	c.rollbackPrepared(ctx, parts)
+	return fmt.Errorf("failed to record decision: %w", err)
+}
+
+// claimDecision records the decision for the distributed transaction id unless one is already recorded.
+// It reports whether the decision was recorded.
+func (c *Coordinator) claimDecision(ctx context.Context, id string, participants []string, decision string) (bool, error) {
+	tag, err := c.conns[c.logPool].Exec(ctx, "INSERT INTO "+decisionLogTable+
+		" (id, participants, decision) VALUES ($1, $2, '"+decision+"') ON CONFLICT (id) DO NOTHING", id, participants)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// commitPrepared runs the second phase: it commits the prepared transaction of every participant,
+// then forgets the decision. It carries on after a cancellation of the context, as the outcome is decided.
+func (c *Coordinator) commitPrepared(ctx context.Context, id string, parts []*participant) error {
+	detached := context.WithoutCancel(ctx)
+
+	var errs []error
+	for _, part := range parts {
+		if _, err := part.conn.Exec(detached, "COMMIT PREPARED "+quoteLiteral(part.gid)); err != nil {
+			errs = append(errs, fmt.Errorf("pool %q: %w", part.name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: failed to commit prepared transactions: %w", ErrInDoubt, errors.Join(errs...))
+	}
+
+	// A record left behind is removed by Recover.
+	_, _ = c.conns[c.logPool].Exec(detached, "DELETE FROM "+decisionLogTable+" WHERE id = $1", id)
+	return nil
+}
+
+// rollback rolls back the transactions of the participants that have not been prepared.
+func (c *Coordinator) rollback(ctx context.Context, parts []*participant) {
+	detached := context.WithoutCancel(ctx)
+	for _, part := range parts {
+		_ = part.tx.Rollback(detached)
+	}
+}
+
+// rollbackPrepared rolls back the prepared transactions of the participants.
+// Those that fail are left to Recover, which aborts them as no decision is recorded.
+func (c *Coordinator) rollbackPrepared(ctx context.Context, parts []*participant) {
+	detached := context.WithoutCancel(ctx)
+	for _, part := range parts {
+		_, _ = part.conn.Exec(detached, "ROLLBACK PREPARED "+quoteLiteral(part.gid))
+	}
+}
+
+// ListPrepared lists the prepared transactions of the current database of every pool
+// that were prepared at least olderThan ago. Transactions found there for a long time
+// are orphans: their coordinator crashed, or they were not prepared by a Coordinator at all.
+func (c *Coordinator) ListPrepared(ctx context.Context, olderThan time.Duration) ([]PreparedTx, error) {
+	var list []PreparedTx
+	for _, name := range c.poolNames() {
+		rows, err := c.conns[name].Query(ctx, `SELECT gid, prepared, owner, database
+FROM pg_prepared_xacts
+WHERE database = current_database() AND prepared <= now() - make_interval(secs => $1)
+ORDER BY prepared`, olderThan.Seconds())
+		if err != nil {
+			return nil, fmt.Errorf("pool %q: %w", name, err)
+		}
+
+		for rows.Next() {
+			ptx := PreparedTx{Pool: name}
+			if err = rows.Scan(&ptx.GID, &ptx.Prepared, &ptx.Owner, &ptx.Database); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			_, pool, ok := c.parseGID(ptx.GID)
+			// Pools sharing a database list the same transactions: keep them on the pool that prepared them.
+			if ok && pool != name {
+				continue
+			}
+			ptx.Managed = ok
+			list = append(list, ptx)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return list, nil
+}
+
+// Recover resolves the transactions left prepared by crashed or failed Coordinators, once they
+// were prepared at least olderThan ago, so that the transactions still in progress are left alone.
+// Those with a recorded decision to commit are committed. For the others, the decision to abort is
+// claimed atomically, so that their Coordinator can no longer decide to commit, and they are rolled back.
+// The decisions to commit no longer needed are removed from the log; those to abort are kept
+// for the abort retention (see WithAbortRetention). It returns the transactions it resolved.
+func (c *Coordinator) Recover(ctx context.Context, olderThan time.Duration) ([]PreparedTx, error) {
+	list, err := c.ListPrepared(ctx, olderThan)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		resolved []PreparedTx
+		pending  = []string{}
+		errs     []error
+	)
+	for _, ptx := range list {
+		if !ptx.Managed {
+			continue
+		}
+		id, _, _ := c.parseGID(ptx.GID)
+
+		var decision string
+		if decision, err = c.recoverDecision(ctx, id, ptx.Pool); err != nil {
+			return resolved, err
+		}
+
+		sql := "ROLLBACK PREPARED "
+		if decision == decisionCommit {
+			sql = "COMMIT PREPARED "
+		}
+		if _, err = c.conns[ptx.Pool].Exec(ctx, sql+quoteLiteral(ptx.GID)); err != nil {
+			pending = append(pending, id)
+			errs = append(errs, fmt.Errorf("pool %q, transaction %q: %w", ptx.Pool, ptx.GID, err))
+			continue
+		}
+		resolved = append(resolved, ptx)
+	}
+
+	// The decisions to abort outlive those to commit, so that a stalled Coordinator cannot claim a commit
+	// once the participants it had prepared were rolled back.
+	_, err = c.conns[c.logPool].Exec(ctx, "DELETE FROM "+decisionLogTable+` WHERE id <> ALL($2) AND (
+    decision = '`+decisionCommit+`' AND decided_at <= now() - make_interval(secs => $1)
+    OR decision = '`+decisionAbort+`' AND $3 > 0 AND decided_at <= now() - make_interval(secs => $3))`,
+		olderThan.Seconds(), pending, c.abortRetention.Seconds())
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	return resolved, errors.Join(errs...)
+}
+
+// recoverDecision claims the decision to abort the distributed transaction id, found prepared on pool,
+// and returns the decision that won: abort if the claim succeeded, the recorded one otherwise.
+func (c *Coordinator) recoverDecision(ctx context.Context, id, pool string) (string, error) {
+	claimed, err := c.claimDecision(ctx, id, []string{pool}, decisionAbort)
+	if err != nil || claimed {
+		return decisionAbort, err
+	}
+
+	var decision string
+	err = c.conns[c.logPool].QueryRow(ctx,
+		"SELECT decision FROM "+decisionLogTable+" WHERE id = $1", id).Scan(&decision)
+	return decision, err
+}
+
+// poolNames returns the names of the pools of the Coordinator, sorted.
+func (c *Coordinator) poolNames() []string {
+	names := make([]string, 0, len(c.conns))
+	for name := range c.conns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// gid returns the identifier of the prepared transaction of the pool in the distributed transaction id.
+func (c *Coordinator) gid(id, pool string) string {
+	return c.gidPrefix + ":" + id + ":" + pool
+}
+
+// parseGID returns the distributed transaction id and the pool of a gid made by the Coordinator.
+func (c *Coordinator) parseGID(gid string) (id, pool string, ok bool) {
+	rest, found := strings.CutPrefix(gid, c.gidPrefix+":")
+	if !found {
+		return "", "", false
+	}
+	return strings.Cut(rest, ":")
+}
+
+// newTxID returns a random distributed transaction id.
+func newTxID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// quoteLiteral quotes s as an SQL string literal.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,246 @@
+package pgx
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+// preparedRows returns the given transactions as the rows of pg_prepared_xacts.
+type preparedRows struct {
+	pgx.Rows
+	gids []string
+	next int
+}
+
+func (r *preparedRows) Next() bool {
+	r.next++
+	return r.next <= len(r.gids)
+}
+
+func (r *preparedRows) Scan(dest ...interface{}) error {
+	*dest[0].(*string) = r.gids[r.next-1]
+	*dest[1].(*time.Time) = time.Now().Add(-time.Hour)
+	*dest[2].(*string) = "postgres"
+	*dest[3].(*string) = "app"
+	return nil
+}
+
+func (r *preparedRows) Err() error { return nil }
+
+func (r *preparedRows) Close() {}
+
+// sqlPrefix matches the SQL statements starting with prefix.
+func sqlPrefix(prefix string) interface{} {
+	return mock.MatchedBy(func(sql string) bool {
+		return strings.HasPrefix(sql, prefix)
+	})
+}
+
+// CoordinatorTestSuite defines the structure for the test suite.
+type CoordinatorTestSuite struct {
+	suite.Suite
+}
+
+// TestCommit checks that both transactions are prepared, the decision is recorded and they are committed.
+func (t *CoordinatorTestSuite) TestCommit() {
+	orders, billing := new(ConnMock), new(ConnMock)
+	ordersTx, billingTx := new(TxMock), new(TxMock)
+	tag := pgconn.CommandTag("OK")
+
+	orders.On("Begin", mock.Anything).Return(ordersTx, nil)
+	billing.On("Begin", mock.Anything).Return(billingTx, nil)
+	ordersTx.On("Exec", mock.Anything, sqlPrefix("PREPARE TRANSACTION 'pgsql2pc:")).Return(tag, nil)
+	billingTx.On("Exec", mock.Anything, sqlPrefix("PREPARE TRANSACTION 'pgsql2pc:")).Return(tag, nil)
+	ordersTx.On("Rollback", mock.Anything).Return(nil)
+	billingTx.On("Rollback", mock.Anything).Return(nil)
+	orders.On("Exec", mock.Anything, sqlPrefix("INSERT INTO tx_decision_log"), mock.Anything, []string{"orders", "billing"}).
+		Return(pgconn.CommandTag("INSERT 0 1"), nil)
+	orders.On("Exec", mock.Anything, sqlPrefix("COMMIT PREPARED 'pgsql2pc:")).Return(tag, nil)
+	billing.On("Exec", mock.Anything, sqlPrefix("COMMIT PREPARED 'pgsql2pc:")).Return(tag, nil)
+	orders.On("Exec", mock.Anything, sqlPrefix("DELETE FROM tx_decision_log"), mock.Anything).Return(tag, nil)
+
+	c := newCoordinator(map[string]Conn{"orders": orders, "billing": billing}, []CoordinatorOption{WithDecisionLogPool("orders")})
+	billingManager := NewTxManagerFromConn(billing)
+
+	committed := false
+	err := c.WithDistributedTx(context.Background(), []string{"orders", "billing"}, func(ctx context.Context, txs map[string]Tx) error {
+		t.Equal(ordersTx, txs["orders"])
+		// A TxManager of a participant joins its transaction.
+		t.Equal(billingTx, billingManager.Querier(ctx))
+		return OnCommit(ctx, func(context.Context) { committed = true })
+	})
+
+	t.NoError(err)
+	t.True(committed)
+	orders.AssertExpectations(t.T())
+	billing.AssertExpectations(t.T())
+	ordersTx.AssertExpectations(t.T())
+	billingTx.AssertExpectations(t.T())
+}
+
+// TestPrepareFailure checks that a failed prepare rolls back every participant without recording a decision.
+func (t *CoordinatorTestSuite) TestPrepareFailure() {
+	orders, billing := new(ConnMock), new(ConnMock)
+	ordersTx, billingTx := new(TxMock), new(TxMock)
+	tag := pgconn.CommandTag("OK")
+	prepareErr := errors.New("prepared transactions are disabled")
+
+	orders.On("Begin", mock.Anything).Return(ordersTx, nil)
+	billing.On("Begin", mock.Anything).Return(billingTx, nil)
+	ordersTx.On("Exec", mock.Anything, sqlPrefix("PREPARE TRANSACTION")).Return(tag, nil)
+	billingTx.On("Exec", mock.Anything, sqlPrefix("PREPARE TRANSACTION")).Return(nil, prepareErr)
+	ordersTx.On("Rollback", mock.Anything).Return(nil)
+	billingTx.On("Rollback", mock.Anything).Return(nil)
+	orders.On("Exec", mock.Anything, sqlPrefix("ROLLBACK PREPARED 'pgsql2pc:")).Return(tag, nil)
+
+	c := newCoordinator(map[string]Conn{"orders": orders, "billing": billing}, []CoordinatorOption{WithDecisionLogPool("orders")})
+
+	var rollbackErr error
+	err := c.WithDistributedTx(context.Background(), []string{"orders", "billing"}, func(ctx context.Context, txs map[string]Tx) error {
+		return OnRollback(ctx, func(_ context.Context, err error) { rollbackErr = err })
+	})
+
+	t.ErrorIs(err, prepareErr)
+	t.ErrorIs(rollbackErr, prepareErr)
+	orders.AssertExpectations(t.T())
+	billing.AssertExpectations(t.T())
+	ordersTx.AssertExpectations(t.T())
+	billingTx.AssertExpectations(t.T())
+	orders.AssertNotCalled(t.T(), "Exec", mock.Anything, sqlPrefix("INSERT"), mock.Anything, mock.Anything)
+}
+
+// TestPrepareFailureSavepointHooks checks that the hooks registered in a savepoint of a participant
+// are handed over to the distributed transaction, instead of running when the savepoint is released.
+func (t *CoordinatorTestSuite) TestPrepareFailureSavepointHooks() {
+	orders, billing := new(ConnMock), new(ConnMock)
+	ordersTx, billingTx, billingSp := new(TxMock), new(TxMock), new(TxMock)
+	tag := pgconn.CommandTag("OK")
+	prepareErr := errors.New("prepared transactions are disabled")
+
+	orders.On("Begin", mock.Anything).Return(ordersTx, nil)
+	billing.On("Begin", mock.Anything).Return(billingTx, nil)
+	billingTx.On("Begin", mock.Anything).Return(billingSp, nil)
+	billingSp.On("Commit", mock.Anything).Return(nil)
+	ordersTx.On("Exec", mock.Anything, sqlPrefix("PREPARE TRANSACTION")).Return(tag, nil)
+	billingTx.On("Exec", mock.Anything, sqlPrefix("PREPARE TRANSACTION")).Return(nil, prepareErr)
+	ordersTx.On("Rollback", mock.Anything).Return(nil)
+	billingTx.On("Rollback", mock.Anything).Return(nil)
+	orders.On("Exec", mock.Anything, sqlPrefix("ROLLBACK PREPARED 'pgsql2pc:")).Return(tag, nil)
+
+	c := newCoordinator(map[string]Conn{"orders": orders, "billing": billing}, []CoordinatorOption{WithDecisionLogPool("orders")})
+	billingManager := NewTxManagerFromConn(billing)
+
+	var committed bool
+	var rollbackErr error
+	err := c.WithDistributedTx(context.Background(), []string{"orders", "billing"}, func(ctx context.Context, txs map[string]Tx) error {
+		return billingManager.WithNestedTx(ctx, func(ctx context.Context, tx Tx) error {
+			t.Equal(billingSp, tx)
+			_ = OnCommit(ctx, func(context.Context) { committed = true })
+			return OnRollback(ctx, func(_ context.Context, err error) { rollbackErr = err })
+		})
+	})
+
+	t.ErrorIs(err, prepareErr)
+	t.False(committed)
+	t.ErrorIs(rollbackErr, prepareErr)
+	orders.AssertExpectations(t.T())
+	billing.AssertExpectations(t.T())
+	ordersTx.AssertExpectations(t.T())
+	billingTx.AssertExpectations(t.T())
+	billingSp.AssertExpectations(t.T())
+}
+
+// TestRecover checks that a prepared transaction with a recorded decision is committed,
+// and that a transaction prepared by someone else is left alone.
+func (t *CoordinatorTestSuite) TestRecover() {
+	conn := new(ConnMock)
+	rowMock := new(RowMock)
+	tag := pgconn.CommandTag("OK")
+
+	conn.On("Query", mock.Anything, sqlPrefix("SELECT gid, prepared, owner, database"), time.Minute.Seconds()).
+		Return(&preparedRows{gids: []string{"pgsql2pc:abc:default", "foreign"}}, nil)
+	// The decision to commit was recorded, so the claim of the abort loses.
+	conn.On("Exec", mock.Anything, sqlPrefix("INSERT INTO tx_decision_log"), "abc", []string{"default"}).
+		Return(pgconn.CommandTag("INSERT 0 0"), nil)
+	conn.On("QueryRow", mock.Anything, sqlPrefix("SELECT decision"), "abc").Return(rowMock)
+	rowMock.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(0).(*string) = "commit"
+	}).Return(nil)
+	conn.On("Exec", mock.Anything, "COMMIT PREPARED 'pgsql2pc:abc:default'").Return(tag, nil)
+	conn.On("Exec", mock.Anything, sqlPrefix("DELETE FROM tx_decision_log"), time.Minute.Seconds(), []string{}, defaultAbortRetention.Seconds()).
+		Return(tag, nil)
+
+	c := newCoordinator(map[string]Conn{"default": conn}, nil)
+
+	resolved, err := c.Recover(context.Background(), time.Minute)
+
+	t.NoError(err)
+	t.Equal(1, len(resolved))
+	t.Equal("pgsql2pc:abc:default", resolved[0].GID)
+	conn.AssertExpectations(t.T())
+	rowMock.AssertExpectations(t.T())
+}
+
+// TestRecoverBeforeDecide checks that a transaction aborted by Recover between the phases can no longer be
+// committed by its Coordinator, which rolls it back instead.
+func (t *CoordinatorTestSuite) TestRecoverBeforeDecide() {
+	conn := new(ConnMock)
+	tx := new(TxMock)
+	tag := pgconn.CommandTag("OK")
+	c := newCoordinator(map[string]Conn{"default": conn}, nil)
+
+	// The decision log, in which the first decision recorded for an id wins.
+	decisions := map[string]string{}
+	claim := func(args mock.Arguments) {
+		id := args.Get(2).(string)
+		if _, ok := decisions[id]; !ok {
+			decisions[id] = strings.Split(args.Get(1).(string), "'")[1]
+			conn.On("Exec", mock.Anything, sqlPrefix("INSERT INTO tx_decision_log"), id, mock.Anything).
+				Return(pgconn.CommandTag("INSERT 0 0"), nil)
+		}
+	}
+	var recovered []PreparedTx
+	conn.On("Begin", mock.Anything).Return(tx, nil)
+	tx.On("Exec", mock.Anything, sqlPrefix("PREPARE TRANSACTION 'pgsql2pc:")).Run(func(args mock.Arguments) {
+		gid := strings.Trim(strings.TrimPrefix(args.Get(1).(string), "PREPARE TRANSACTION "), "'")
+		conn.On("Query", mock.Anything, sqlPrefix("SELECT gid, prepared, owner, database"), mock.Anything).
+			Return(&preparedRows{gids: []string{gid}}, nil)
+
+		// Recover runs right after the prepare, before the decision is recorded.
+		var err error
+		recovered, err = c.Recover(context.Background(), 0)
+		t.NoError(err)
+	}).Return(tag, nil)
+	tx.On("Rollback", mock.Anything).Return(nil)
+	conn.On("Exec", mock.Anything, sqlPrefix("INSERT INTO tx_decision_log"), mock.Anything, mock.Anything).
+		Run(claim).Return(pgconn.CommandTag("INSERT 0 1"), nil).Once()
+	conn.On("Exec", mock.Anything, sqlPrefix("ROLLBACK PREPARED 'pgsql2pc:")).Return(tag, nil)
+	conn.On("Exec", mock.Anything, sqlPrefix("DELETE FROM tx_decision_log"), mock.Anything, mock.Anything, mock.Anything).Return(tag, nil)
+
+	var rollbackErr error
+	err := c.WithDistributedTx(context.Background(), []string{"default"}, func(ctx context.Context, txs map[string]Tx) error {
+		return OnRollback(ctx, func(_ context.Context, err error) { rollbackErr = err })
+	})
+
+	t.ErrorIs(err, ErrPresumedAbort)
+	t.ErrorIs(rollbackErr, ErrPresumedAbort)
+	t.Len(recovered, 1)
+	id, _, _ := c.parseGID(recovered[0].GID)
+	t.Equal(map[string]string{id: "abort"}, decisions)
+	conn.AssertNotCalled(t.T(), "Exec", mock.Anything, sqlPrefix("COMMIT PREPARED"))
+	conn.AssertExpectations(t.T())
+	tx.AssertExpectations(t.T())
+}
+
+// TestCoordinatorSuite runs the test suite.
+func TestCoordinatorSuite(t *testing.T) {
+	suite.Run(t, new(CoordinatorTestSuite))
+}
@@ -0,0 +1,82 @@
+package pgx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const defaultRollbackTimeout = 5 * time.Second
+
+var (
+	// ErrCommitFailed wraps the error of a failed commit.
+	ErrCommitFailed = errors.New("commit failed")
+
+	// ErrRollbackFailed wraps the error of a failed rollback. It is joined to the error that caused the rollback.
+	ErrRollbackFailed = errors.New("rollback failed")
+)
+
+// PanicError reports a panic that occurred in a function executed within a transaction,
+// when the Transactor is configured with WithPanicRecovery.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic in transaction: %v", e.Value)
+}
+
+// WithRollbackTimeout is an option to bound the time a rollback may take (default: 5s).
+// The rollback runs on a context detached from the cancellation of the caller's one,
+// so that a transaction is still rolled back when its context has been canceled.
+func WithRollbackTimeout(timeout time.Duration) Option {
+	return func(t *Transactor) {
+		t.rollbackTimeout = timeout
+	}
+}
+
+// WithPanicRecovery is an option to return the panics of the functions executed within transactions
+// as a *PanicError carrying the stack trace, instead of re-panicking once the transaction is rolled back.
+func WithPanicRecovery() Option {
+	return func(t *Transactor) {
+		t.recoverPanics = true
+	}
+}
+
+// recoverPanic turns p into a *PanicError replacing err when the Transactor recovers panics.
+// It returns the panic to re-throw, if any, and the error of the function.
+func (t *Transactor) recoverPanic(p any, err error) (any, error) {
+	if p == nil || !t.recoverPanics {
+		return p, err
+	}
+	return nil, &PanicError{Value: p, Stack: debug.Stack()}
+}
+
+// rollbackContext returns a context for rolling back the transaction in ctx that is not canceled along with ctx,
+// bounded by the rollback timeout.
+func (t *Transactor) rollbackContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := t.rollbackTimeout
+	if timeout <= 0 {
+		timeout = defaultRollbackTimeout
+	}
+	return context.WithTimeout(context.WithoutCancel(ctx), timeout)
+}
+
+// commitError wraps the error of a failed commit into ErrCommitFailed.
+func commitError(err error) error {
+	return fmt.Errorf("%w: %w", ErrCommitFailed, err)
+}
+
+// rollbackError joins the error of a failed rollback, wrapped into ErrRollbackFailed, to err.
+// A transaction that was already closed is not reported.
+func rollbackError(err, rollbackErr error) error {
+	if rollbackErr == nil || errors.Is(rollbackErr, pgx.ErrTxClosed) {
+		return err
+	}
+	return errors.Join(err, fmt.Errorf("%w: %w", ErrRollbackFailed, rollbackErr))
+}
@@ -0,0 +1,128 @@
+package pgx
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// txInfo describes a transaction or savepoint started by a Transactor.
+type txInfo struct {
+	tx    Tx
+	depth int
+	root  *txInfo
+
+	// The id and start time of the outermost transaction, fetched on first use.
+	mu      sync.Mutex
+	fetched bool
+	id      int64
+	start   time.Time
+}
+
+// txInfoKey is used for storing the innermost transaction or savepoint of a Transactor in the context.
+// It is namespaced by the connection of the Transactor, the zero key holding the innermost one
+// of any Transactor.
+type txInfoKey struct {
+	conn Conn
+}
+
+// devMode enables the guards of MustNotBeInTx.
+var devMode atomic.Bool
+
+// SetDevMode enables or disables the development mode, in which MustNotBeInTx panics inside transactions.
+func SetDevMode(enabled bool) {
+	devMode.Store(enabled)
+}
+
+// InTx reports whether the context carries a transaction started by a Transactor.
+func InTx(ctx context.Context) bool {
+	_, ok := ctx.Value(txInfoKey{}).(*txInfo)
+	return ok
+}
+
+// TxDepth returns the nesting level of the innermost transaction in the context: 0 outside of transactions,
+// 1 in a transaction, and one more for each savepoint started by WithNestedTx.
+func TxDepth(ctx context.Context) int {
+	if info, ok := ctx.Value(txInfoKey{}).(*txInfo); ok {
+		return info.depth
+	}
+	return 0
+}
+
+// TxFromContext returns the innermost transaction or savepoint in the context.
+func TxFromContext(ctx context.Context) (Tx, bool) {
+	if info, ok := ctx.Value(txInfoKey{}).(*txInfo); ok {
+		return info.tx, true
+	}
+	return nil, false
+}
+
+// TxID returns the id of the outermost transaction in the context, as reported by txid_current().
+// It is fetched on first use and cached for the rest of the transaction.
+func TxID(ctx context.Context) (int64, error) {
+	root, err := fetchTxInfo(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return root.id, nil
+}
+
+// TxStartTime returns the time the outermost transaction in the context started at, as reported by now().
+// It is fetched on first use and cached for the rest of the transaction.
+func TxStartTime(ctx context.Context) (time.Time, error) {
+	root, err := fetchTxInfo(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return root.start, nil
+}
+
+// MustNotBeInTx panics in development mode when called inside a transaction. Call it before slow
+// or external work, such as HTTP requests, that must not hold a transaction open.
+// Outside of development mode, it does nothing.
+func MustNotBeInTx(ctx context.Context) {
+	if !devMode.Load() {
+		return
+	}
+	if depth := TxDepth(ctx); depth > 0 {
+		panic(fmt.Sprintf("pgx: called inside a transaction (depth %d)", depth))
+	}
+}
+
+// withTxInfo stores tx in the context as the innermost transaction, or savepoint when the
+// Transactor already has a transaction in the context.
+func (t *Transactor) withTxInfo(ctx context.Context, tx Tx) context.Context {
+	info := &txInfo{tx: tx, depth: 1}
+	info.root = info
+	if parent, ok := ctx.Value(txInfoKey{conn: t.conn}).(*txInfo); ok {
+		info.depth = parent.depth + 1
+		info.root = parent.root
+	}
+
+	ctx = context.WithValue(ctx, txInfoKey{conn: t.conn}, info)
+	return context.WithValue(ctx, txInfoKey{}, info)
+}
+
+// fetchTxInfo returns the outermost transaction in the context, with its id and start time fetched.
+func fetchTxInfo(ctx context.Context) (*txInfo, error) {
+	info, ok := ctx.Value(txInfoKey{}).(*txInfo)
+	if !ok {
+		return nil, ErrNoTransaction
+	}
+
+	root := info.root
+	root.mu.Lock()
+	defer root.mu.Unlock()
+
+	if !root.fetched {
+		// Queried on the innermost transaction, as the outer ones are not usable while a savepoint is open.
+		if err := info.tx.QueryRow(ctx, "SELECT txid_current(), now()").Scan(&root.id, &root.start); err != nil {
+			return nil, err
+		}
+		root.fetched = true
+	}
+
+	return root, nil
+}
@@ -3,7 +3,9 @@ package pgx
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync/atomic"
+	"time"
 
 	"github.com/i4erkasov/go-pgsql/pgxpool"
 	"github.com/jackc/pgx/v4"
@@ -17,59 +19,159 @@ type Row = pgx.Row
 
 // Conn defines the interface for connection
 type Conn interface {
+	Querier
 	Begin(ctx context.Context) (Tx, error)
+	BeginTx(ctx context.Context, txOptions TxOptions) (Tx, error)
 }
 
 // TxManager defines the interface for transaction management.
 type TxManager interface {
 	WithTx(ctx context.Context, fn func(context.Context, Tx) error) error
 	WithNestedTx(ctx context.Context, tFunc func(context.Context, Tx) error) error
+	WithTxOptions(ctx context.Context, opts TxOptions, fn func(context.Context, Tx) error) error
+	WithReadOnlyTx(ctx context.Context, fn func(context.Context, Tx) error) error
+	WithAdvisoryLock(ctx context.Context, lock AdvisoryLock, fn func(context.Context, Tx) error) error
+	WithPropagation(ctx context.Context, propagation Propagation, fn func(context.Context, Tx) error) error
+	Querier(ctx context.Context) Querier
+	Notify(ctx context.Context, channel, payload string) error
+	Savepoint(ctx context.Context, name string) error
+	RollbackTo(ctx context.Context, name string) error
+	ReleaseSavepoint(ctx context.Context, name string) error
 }
 
 // Transactor is a concrete implementation of TxManagerInterface using pgxpool.
 type Transactor struct {
-	conn Conn
+	conn            Conn
+	name            string
+	replica         func() (Conn, int)
+	replicaFallback bool
+	retry           *RetryPolicy
+	timeouts        Timeouts
+	tracer          Tracer
+	metrics         MetricsSink
+	rollbackTimeout time.Duration
+	recoverPanics   bool
+	hookPanicked    func(context.Context, error)
 }
 
+// Option defines the type for functional options for Transactor configuration.
+type Option func(*Transactor)
+
 // NewTxManager creates a new instance of TxManager with a given registry.
-// It uses the master connection pool for managing transactions.
-func NewTxManager(registry *pgxpool.Registry) (TxManager, error) {
-	pools, err := registry.Pools()
+// It uses the master connection pool for managing transactions,
+// and the slave connection pools for read only transactions.
+func NewTxManager(registry *pgxpool.Registry, opts ...Option) (TxManager, error) {
+	return NewTxManagerFor(registry, pgxpool.DEFAULT, opts...)
+}
+
+// NewTxManagerFor creates a new instance of TxManager for the pool with the given name in the registry.
+func NewTxManagerFor(registry *pgxpool.Registry, name string, opts ...Option) (TxManager, error) {
+	pools, err := registry.GetPoolName(name)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Transactor{
-		pools.Master(),
-	}, nil
+	return newPoolsTransactor(name, pools, opts), nil
 }
 
-type txContextKey struct{}
-type txContextCounterKey struct{}
+// NewTxManagerFromPools creates a new instance of TxManager with given master/slave pools.
+func NewTxManagerFromPools(pools *pgxpool.Pools, opts ...Option) TxManager {
+	return newPoolsTransactor("", pools, opts)
+}
 
-var (
-	// txKey is a private key used for storing transaction context.
-	txKey = txContextKey{}
+// NewTxManagerFromPool creates a new instance of TxManager with a single connection pool.
+func NewTxManagerFromPool(pool *pgxpool.Pool, opts ...Option) TxManager {
+	return newTransactor(pool, opts)
+}
 
-	// txCounterKey is used for storing the transaction counter in the context.
-	txCounterKey = txContextCounterKey{}
+// NewTxManagerFromConn creates a new instance of TxManager with any Conn, such as *pgx.Conn.
+// The conn identifies the transactions of the manager in the context, so it must be
+// comparable, as pointer types are.
+func NewTxManagerFromConn(conn Conn, opts ...Option) TxManager {
+	return newTransactor(conn, opts)
+}
 
-	// ErrNoTransaction is the error used when no transaction is found in the context.
-	ErrNoTransaction = errors.New("no transaction in context")
-)
+// newPoolsTransactor creates a Transactor for the named master/slave pools.
+func newPoolsTransactor(name string, pools *pgxpool.Pools, opts []Option) *Transactor {
+	t := newTransactor(pools.Master(), opts)
+	t.name = name
+	if pools.HasSlaves() {
+		t.replica = func() (Conn, int) {
+			return pools.SlaveNode()
+		}
+	}
+
+	return t
+}
+
+// newTransactor creates a Transactor with conn and applies the options to it.
+func newTransactor(conn Conn, opts []Option) *Transactor {
+	t := &Transactor{
+		conn:            conn,
+		replicaFallback: true,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+// txContextKey is the key transactions are stored under in the context.
+// It is namespaced by the connection of the Transactor, so that managers for different
+// pools never join each other's transactions, while managers for the same pool do.
+type txContextKey struct {
+	conn Conn
+}
+
+// txCounterKey is used for storing the transaction counter of a Transactor in the context.
+type txCounterKey struct {
+	conn Conn
+}
+
+// ErrNoTransaction is the error used when no transaction is found in the context.
+var ErrNoTransaction = errors.New("no transaction in context")
+
+// txKey returns the private key used for storing transaction context.
+func (t *Transactor) txKey() txContextKey {
+	return txContextKey{conn: t.conn}
+}
 
-// Begin starts a new transaction and stores it in the context.
-func (t *Transactor) begin(ctx context.Context) (context.Context, error) {
-	tx, err := t.conn.Begin(ctx)
+// Begin starts a new transaction on conn, the given node of the pool, with the given options
+// and stores it in the context. Default options start the transaction with a plain BEGIN.
+// The configured timeouts are applied right after it.
+func (t *Transactor) begin(ctx context.Context, conn Conn, node int, opts TxOptions) (context.Context, error) {
+	var (
+		tx  Tx
+		err error
+	)
+	ctx = t.traceTxStart(ctx, node, opts)
+	if opts == (TxOptions{}) {
+		tx, err = conn.Begin(ctx)
+	} else {
+		tx, err = conn.BeginTx(ctx, opts)
+	}
 	if err != nil {
+		t.traceTxEnd(ctx, false, err)
 		return nil, err
 	}
-	return context.WithValue(ctx, txKey, tx), nil
+	if err = t.setTimeouts(ctx, tx); err != nil {
+		rollbackCtx, cancel := t.rollbackContext(ctx)
+		err = rollbackError(err, tx.Rollback(rollbackCtx))
+		cancel()
+		t.traceTxEnd(ctx, false, err)
+		return nil, err
+	}
+	ctx = context.WithValue(ctx, t.txOptionsKey(), opts)
+	ctx, _ = withTxHooks(ctx, t.conn)
+	ctx = t.withTxUnit(ctx, tx)
+	ctx = t.withTxInfo(ctx, tx)
+	return context.WithValue(ctx, t.txKey(), tx), nil
 }
 
 // Commit commits the transaction stored in the context.
 func (t *Transactor) commit(ctx context.Context) error {
-	tx, ok := ctx.Value(txKey).(Tx)
+	tx, ok := ctx.Value(t.txKey()).(Tx)
 	if !ok {
 		return ErrNoTransaction
 	}
@@ -78,41 +180,135 @@ func (t *Transactor) commit(ctx context.Context) error {
 
 // Rollback aborts the transaction stored in the context.
 func (t *Transactor) rollback(ctx context.Context) error {
-	tx, ok := ctx.Value(txKey).(Tx)
+	tx, ok := ctx.Value(t.txKey()).(Tx)
 	if !ok {
 		return ErrNoTransaction
 	}
 	return tx.Rollback(ctx)
 }
 
+// finish ends the transaction or savepoint stored in ctx once the function has returned err
+// or panicked with p: it commits on success and rolls back otherwise, then runs the hooks
+// registered for it. The statements queued with Queue are sent before the commit.
+// The returned error replaces err: a failed commit is wrapped into ErrCommitFailed,
+// and a failed rollback is joined to err wrapped into ErrRollbackFailed.
+func (t *Transactor) finish(parent, ctx context.Context, err error, p any) error {
+	if p == nil && err == nil {
+		// Send the statements queued in the unit of work before committing
+		err = t.flushTxUnit(ctx)
+	}
+
+	if p == nil && err == nil {
+		// err is nil; if Commit returns error update err
+		if err = t.commit(ctx); err != nil {
+			err = commitError(err)
+			t.traceTxEnd(ctx, false, err)
+			t.afterRollback(parent, t.hooks(ctx), err)
+			return err
+		}
+		t.traceTxEnd(ctx, true, nil)
+		t.afterCommit(parent, t.hooks(ctx))
+		return nil
+	}
+
+	// err is non-nil or the function panicked; rollback the transaction
+	// on a context that is still usable if ctx has been canceled
+	rollbackCtx, cancel := t.rollbackContext(ctx)
+	rollbackErr := t.rollback(rollbackCtx)
+	cancel()
+
+	if p != nil {
+		panicErr := rollbackError(fmt.Errorf("panic in transaction: %v", p), rollbackErr)
+		t.traceTxEnd(ctx, false, panicErr)
+		t.afterRollback(parent, t.hooks(ctx), panicErr)
+		return err
+	}
+
+	err = rollbackError(err, rollbackErr)
+	t.traceTxEnd(ctx, false, err)
+	t.afterRollback(parent, t.hooks(ctx), err)
+
+	return err
+}
+
 // WithTx executes a function within the context of a transaction.
 // This method checks if there is already an ongoing transaction in the context.
 // If not, it starts a new transaction and then executes the provided function.
 // After the function execution, it commits the transaction if no errors occurred,
 // or rollbacks in case of an error or panic.
 // The transaction object is passed to the function, allowing direct transaction control.
-func (t *Transactor) WithTx(ctx context.Context, tFunc func(context.Context, Tx) error) (err error) {
+func (t *Transactor) WithTx(ctx context.Context, tFunc func(context.Context, Tx) error) error {
+	return t.WithTxOptions(ctx, TxOptions{}, tFunc)
+}
+
+// WithTxOptions works like WithTx, but starts the transaction with the given
+// isolation level, access mode and deferrable mode.
+// If there is already an ongoing transaction in the context, the function joins it,
+// provided the requested options are compatible with those of the active transaction.
+// Otherwise, ErrIncompatibleTxOptions is returned and the function is not executed.
+// A new transaction is retried according to the retry policy of the Transactor.
+func (t *Transactor) WithTxOptions(ctx context.Context, opts TxOptions, tFunc func(context.Context, Tx) error) error {
+	if _, ok := ctx.Value(t.txKey()).(Tx); !ok {
+		return t.withRetry(ctx, func(ctx context.Context) error {
+			return t.withTxOptions(ctx, t.conn, 0, opts, tFunc)
+		})
+	}
+	return t.withTxOptions(ctx, t.conn, 0, opts, tFunc)
+}
+
+// WithReadOnlyTx executes a function within the context of a READ ONLY transaction.
+// A new transaction is started on a slave connection pool. When there is no slave,
+// it falls back to the master pool, unless disabled with WithReplicaFallback.
+// If there is already an ongoing transaction in the context, the function joins it.
+// Inside a transaction started by WithReadOnlyTx, attempts to nest a read write
+// transaction fail with ErrReadOnlyTx.
+func (t *Transactor) WithReadOnlyTx(ctx context.Context, tFunc func(context.Context, Tx) error) error {
+	opts := TxOptions{AccessMode: ReadOnly}
+	if _, ok := ctx.Value(t.txKey()).(Tx); ok {
+		return t.withTxOptions(ctx, t.conn, 0, opts, tFunc)
+	}
+
+	conn, node, err := t.readConn()
+	if err != nil {
+		return err
+	}
+
+	ctx = context.WithValue(ctx, t.txReadOnlyKey(), true)
+	return t.withRetry(ctx, func(ctx context.Context) error {
+		return t.withTxOptions(ctx, conn, node, opts, tFunc)
+	})
+}
+
+// withTxOptions runs a single attempt of WithTxOptions, starting a new transaction on conn if needed.
+func (t *Transactor) withTxOptions(ctx context.Context, conn Conn, node int, opts TxOptions, tFunc func(context.Context, Tx) error) (err error) {
 	// Check if there is already a transaction in the context
-	tx, ok := ctx.Value(txKey).(Tx)
-	if !ok {
+	tx, ok := ctx.Value(t.txKey()).(Tx)
+	if ok {
+		if err = t.checkReadOnly(ctx, opts); err != nil {
+			return err
+		}
+		active, _ := ctx.Value(t.txOptionsKey()).(TxOptions)
+		if err = checkTxOptions(active, opts); err != nil {
+			return err
+		}
+	} else {
 		// Start a new transaction if there isn't one
-		ctx, err = t.begin(ctx)
+		parent := ctx
+		ctx, err = t.begin(ctx, conn, node, opts)
 		if err != nil {
 			return err
 		}
 		defer func() {
 			// Handle the end of the transaction
-			if p := recover(); p != nil {
-				_ = t.rollback(ctx)
+			var p any
+			p, err = t.recoverPanic(recover(), err)
+			err = t.finish(parent, ctx, err, p)
+			if p != nil {
 				panic(p) // re-throw panic after Rollback
-			} else if err != nil {
-				_ = t.rollback(ctx) // err is non-nil; rollback the transaction
-			} else {
-				err = t.commit(ctx) // err is nil; if Commit returns error update err
 			}
 		}()
 		// Get the transaction object after beginning a new transaction
-		tx = ctx.Value(txKey).(Tx)
+		tx = ctx.Value(t.txKey()).(Tx)
 	}
 
 	// Execute the function passed, providing the transaction object
@@ -123,37 +319,44 @@ func (t *Transactor) WithTx(ctx context.Context, tFunc func(context.Context, Tx)
 
 // WithNestedTx executes a function within the context of a potentially nested transaction.
 // It manages transaction nesting using a counter to track the depth of nested transactions.
-// If there is no active transaction, it starts a new one.
-func (t *Transactor) WithNestedTx(ctx context.Context, tFunc func(context.Context, Tx) error) (err error) {
-	// Start a new transaction or increment the nested transaction counter.
-	ctx, nested, err := t.beginNestedTx(ctx)
+// If there is no active transaction, it starts a new one. Otherwise, the function runs
+// inside a savepoint of the active transaction, so an error or panic rolls back only
+// the work done by this function and leaves the outer transaction usable.
+// Only the outermost transaction is retried according to the retry policy of the Transactor.
+func (t *Transactor) WithNestedTx(ctx context.Context, tFunc func(context.Context, Tx) error) error {
+	if _, ok := ctx.Value(t.txKey()).(Tx); !ok {
+		return t.withRetry(ctx, func(ctx context.Context) error {
+			return t.withNestedTx(ctx, tFunc)
+		})
+	}
+	return t.withNestedTx(ctx, tFunc)
+}
+
+// withNestedTx runs a single attempt of WithNestedTx.
+func (t *Transactor) withNestedTx(ctx context.Context, tFunc func(context.Context, Tx) error) (err error) {
+	// Start a new transaction or a savepoint and increment the nested transaction counter.
+	parent := ctx
+	ctx, err = t.beginNestedTx(ctx)
 	if err != nil {
 		return err
 	}
 
 	defer func() {
 		// Decrement the transaction counter when exiting the function.
-		ctx = tickTxCounter(ctx, -1)
+		ctx = t.tickTxCounter(ctx, -1)
 
-		// Handle any panics or errors, and rollback if necessary.
-		if p := recover(); p != nil {
-			if nested {
-				_ = t.rollback(ctx)
-			}
+		// Handle any panics or errors. For a savepoint, commit releases it
+		// and rollback rolls back to it.
+		var p any
+		p, err = t.recoverPanic(recover(), err)
+		err = t.finish(parent, ctx, err, p)
+		if p != nil {
 			panic(p)
-		} else if err != nil {
-			if nested {
-				_ = t.rollback(ctx)
-			}
-		} else {
-			if nested {
-				err = t.commit(ctx)
-			}
 		}
 	}()
 
 	// Get the transaction object from the context to pass to the function.
-	tx := ctx.Value(txKey).(Tx)
+	tx := ctx.Value(t.txKey()).(Tx)
 
 	// Execute the provided function within the transaction context.
 	err = tFunc(ctx, tx)
@@ -163,36 +366,87 @@ func (t *Transactor) WithNestedTx(ctx context.Context, tFunc func(context.Contex
 
 // beginNestedTx starts a new transaction if one is not already in progress,
 // and manages the transaction counter. If an existing transaction is detected,
-// it increments the counter without starting a new transaction.
-func (t *Transactor) beginNestedTx(ctx context.Context) (context.Context, bool, error) {
+// it starts a pseudo nested transaction (a savepoint) on it and stores it in the context.
+func (t *Transactor) beginNestedTx(ctx context.Context) (context.Context, error) {
 	// Increment the transaction counter and check if a transaction is already in progress.
-	ctx = tickTxCounter(ctx, 1)
-	if _, ok := ctx.Value(txKey).(Tx); ok {
-		// If a transaction is already in progress, use it without starting a new one.
-		return ctx, false, nil
+	ctx = t.tickTxCounter(ctx, 1)
+	if tx, ok := ctx.Value(t.txKey()).(Tx); ok {
+		if err := t.checkReadOnly(ctx, TxOptions{}); err != nil {
+			ctx = t.tickTxCounter(ctx, -1)
+			return ctx, err
+		}
+
+		// Send the statements queued so far, so that they are not tied to the fate of the savepoint.
+		if err := t.flushTxUnit(ctx); err != nil {
+			ctx = t.tickTxCounter(ctx, -1)
+			return ctx, err
+		}
+
+		// If a transaction is already in progress, create a savepoint within it.
+		ctx = t.traceTxStart(ctx, 0, TxOptions{})
+		sp, err := tx.Begin(ctx)
+		if err != nil {
+			t.traceTxEnd(ctx, false, err)
+			ctx = t.tickTxCounter(ctx, -1)
+			return ctx, err
+		}
+		ctx, _ = withTxHooks(ctx, t.conn)
+		ctx = t.withTxUnit(ctx, sp)
+		ctx = t.withTxInfo(ctx, sp)
+		return context.WithValue(ctx, t.txKey(), sp), nil
 	}
 
 	// Start a new transaction if there isn't one already.
-	var err error
-	ctx, err = t.begin(ctx)
+	txCtx, err := t.begin(ctx, t.conn, 0, TxOptions{})
 	if err != nil {
 		// In case of an error, decrement the counter back.
-		ctx = tickTxCounter(ctx, -1)
-		return ctx, false, err
+		ctx = t.tickTxCounter(ctx, -1)
+		return ctx, err
 	}
-	return ctx, true, nil
+	return txCtx, nil
+}
+
+// Savepoint creates a savepoint with the given name in the transaction stored in the context.
+// It is intended for cases where the callback style of WithNestedTx does not fit.
+func (t *Transactor) Savepoint(ctx context.Context, name string) error {
+	return t.execInTx(ctx, "SAVEPOINT "+pgx.Identifier{name}.Sanitize())
+}
+
+// RollbackTo rolls back all work done after the named savepoint was created.
+// The savepoint stays valid and may be rolled back to again.
+func (t *Transactor) RollbackTo(ctx context.Context, name string) error {
+	return t.execInTx(ctx, "ROLLBACK TO SAVEPOINT "+pgx.Identifier{name}.Sanitize())
+}
+
+// ReleaseSavepoint destroys the named savepoint, keeping the work done after it was created.
+func (t *Transactor) ReleaseSavepoint(ctx context.Context, name string) error {
+	return t.execInTx(ctx, "RELEASE SAVEPOINT "+pgx.Identifier{name}.Sanitize())
+}
+
+// execInTx executes a statement in the transaction stored in the context.
+func (t *Transactor) execInTx(ctx context.Context, sql string) error {
+	tx, ok := ctx.Value(t.txKey()).(Tx)
+	if !ok {
+		return ErrNoTransaction
+	}
+	// The queued statements belong before the savepoint operation
+	if err := t.flushTxUnit(ctx); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx, sql)
+	return err
 }
 
 // tickTxCounter safely increments or decrements the transaction counter in the context.
 // Returns the updated context.
-func tickTxCounter(ctx context.Context, val int32) context.Context {
-	count, ok := ctx.Value(txCounterKey).(*int32)
+func (t *Transactor) tickTxCounter(ctx context.Context, val int32) context.Context {
+	count, ok := ctx.Value(txCounterKey{conn: t.conn}).(*int32)
 
 	if !ok {
 		// Initialize the counter if it's not present in the context.
 		var cnt int32
 		count = &cnt
-		ctx = context.WithValue(ctx, txCounterKey, count)
+		ctx = context.WithValue(ctx, txCounterKey{conn: t.conn}, count)
 	}
 
 	// Atomically update the counter.
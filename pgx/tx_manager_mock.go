@@ -15,6 +15,116 @@ type TxManagerMock struct {
 	mock.Mock
 }
 
+// Notify provides a mock function with given fields: ctx, channel, payload
+func (_m *TxManagerMock) Notify(ctx context.Context, channel string, payload string) error {
+	ret := _m.Called(ctx, channel, payload)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Notify")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, channel, payload)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Querier provides a mock function with given fields: ctx
+func (_m *TxManagerMock) Querier(ctx context.Context) Querier {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Querier")
+	}
+
+	var r0 Querier
+	if rf, ok := ret.Get(0).(func(context.Context) Querier); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(Querier)
+		}
+	}
+
+	return r0
+}
+
+// ReleaseSavepoint provides a mock function with given fields: ctx, name
+func (_m *TxManagerMock) ReleaseSavepoint(ctx context.Context, name string) error {
+	ret := _m.Called(ctx, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReleaseSavepoint")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, name)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RollbackTo provides a mock function with given fields: ctx, name
+func (_m *TxManagerMock) RollbackTo(ctx context.Context, name string) error {
+	ret := _m.Called(ctx, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RollbackTo")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, name)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Savepoint provides a mock function with given fields: ctx, name
+func (_m *TxManagerMock) Savepoint(ctx context.Context, name string) error {
+	ret := _m.Called(ctx, name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Savepoint")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, name)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// WithAdvisoryLock provides a mock function with given fields: ctx, lock, fn
+func (_m *TxManagerMock) WithAdvisoryLock(ctx context.Context, lock AdvisoryLock, fn func(context.Context, pgx.Tx) error) error {
+	ret := _m.Called(ctx, lock, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for WithAdvisoryLock")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, AdvisoryLock, func(context.Context, pgx.Tx) error) error); ok {
+		r0 = rf(ctx, lock, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // WithNestedTx provides a mock function with given fields: ctx, tFunc
 func (_m *TxManagerMock) WithNestedTx(ctx context.Context, tFunc func(context.Context, pgx.Tx) error) error {
 	ret := _m.Called(ctx, tFunc)
@@ -33,6 +143,42 @@ func (_m *TxManagerMock) WithNestedTx(ctx context.Context, tFunc func(context.Co
 	return r0
 }
 
+// WithPropagation provides a mock function with given fields: ctx, propagation, fn
+func (_m *TxManagerMock) WithPropagation(ctx context.Context, propagation Propagation, fn func(context.Context, pgx.Tx) error) error {
+	ret := _m.Called(ctx, propagation, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for WithPropagation")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, Propagation, func(context.Context, pgx.Tx) error) error); ok {
+		r0 = rf(ctx, propagation, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// WithReadOnlyTx provides a mock function with given fields: ctx, fn
+func (_m *TxManagerMock) WithReadOnlyTx(ctx context.Context, fn func(context.Context, pgx.Tx) error) error {
+	ret := _m.Called(ctx, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for WithReadOnlyTx")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, func(context.Context, pgx.Tx) error) error); ok {
+		r0 = rf(ctx, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // WithTx provides a mock function with given fields: ctx, fn
 func (_m *TxManagerMock) WithTx(ctx context.Context, fn func(context.Context, pgx.Tx) error) error {
 	ret := _m.Called(ctx, fn)
@@ -51,6 +197,24 @@ func (_m *TxManagerMock) WithTx(ctx context.Context, fn func(context.Context, pg
 	return r0
 }
 
+// WithTxOptions provides a mock function with given fields: ctx, opts, fn
+func (_m *TxManagerMock) WithTxOptions(ctx context.Context, opts pgx.TxOptions, fn func(context.Context, pgx.Tx) error) error {
+	ret := _m.Called(ctx, opts, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for WithTxOptions")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, pgx.TxOptions, func(context.Context, pgx.Tx) error) error); ok {
+		r0 = rf(ctx, opts, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewTxManagerMock creates a new instance of TxManagerMock. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewTxManagerMock(t interface {
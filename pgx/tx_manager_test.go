@@ -4,7 +4,12 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
+	"github.com/i4erkasov/go-pgsql/pgxpool"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	jackcpool "github.com/jackc/pgx/v4/pgxpool"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
@@ -14,6 +19,42 @@ type txManagerTestSuite struct {
 	suite.Suite
 }
 
+// recordingTracer records the transaction events it receives.
+type recordingTracer struct {
+	starts []pgxpool.TraceTxStartData
+	ends   []pgxpool.TraceTxEndData
+}
+
+func (r *recordingTracer) TraceQueryStart(ctx context.Context, _ pgxpool.TraceQueryStartData) context.Context {
+	return ctx
+}
+
+func (r *recordingTracer) TraceQueryEnd(context.Context, pgxpool.TraceQueryEndData) {}
+
+func (r *recordingTracer) TraceTxStart(ctx context.Context, data pgxpool.TraceTxStartData) context.Context {
+	r.starts = append(r.starts, data)
+	return ctx
+}
+
+func (r *recordingTracer) TraceTxEnd(_ context.Context, data pgxpool.TraceTxEndData) {
+	r.ends = append(r.ends, data)
+}
+
+// fakeBatchResults returns the given results for the statements of a batch, in order.
+type fakeBatchResults struct {
+	pgx.BatchResults
+	tags []pgconn.CommandTag
+	errs []error
+	next int
+}
+
+func (r *fakeBatchResults) Exec() (pgconn.CommandTag, error) {
+	r.next++
+	return r.tags[r.next-1], r.errs[r.next-1]
+}
+
+func (r *fakeBatchResults) Close() error { return nil }
+
 func (t *txManagerTestSuite) TestWithTxSuccess() {
 	t.T().Parallel()
 
@@ -181,6 +222,1136 @@ func (t *txManagerTestSuite) TestWithNestedTxConditionalLogic() {
 	rowMock.AssertExpectations(t.T())
 }
 
+// TestWithNestedTxSavepointRollback tests that an error in an inner nested transaction
+// rolls back only its savepoint, while the outer transaction is still committed.
+func (t *txManagerTestSuite) TestWithNestedTxSavepointRollback() {
+	t.T().Parallel()
+
+	connMock := new(ConnMock)
+	txMock := new(TxMock)
+	spMock := new(TxMock)
+
+	connMock.On("Begin", mock.Anything).Return(txMock, nil)
+	// Expect the savepoint to be created on the outer transaction and rolled back.
+	txMock.On("Begin", mock.Anything).Return(spMock, nil)
+	spMock.On("Rollback", mock.Anything).Return(nil)
+	// Expect the outer transaction to be committed despite the inner error.
+	txMock.On("Commit", mock.Anything).Return(nil)
+
+	transactor := Transactor{conn: connMock}
+	innerErr := errors.New("error in inner transaction")
+
+	err := transactor.WithNestedTx(context.Background(), func(ctx context.Context, tx Tx) error {
+		err := transactor.WithNestedTx(ctx, func(ctx context.Context, tx Tx) error {
+			// The inner function must receive the savepoint, not the outer transaction.
+			assert.Same(t.T(), spMock, tx)
+			return innerErr
+		})
+		assert.ErrorIs(t.T(), err, innerErr)
+
+		// The outer caller decides to continue.
+		return nil
+	})
+
+	assert.NoError(t.T(), err)
+	connMock.AssertExpectations(t.T())
+	txMock.AssertExpectations(t.T())
+	spMock.AssertExpectations(t.T())
+}
+
+// TestWithNestedTxSavepointRelease tests that a successful inner nested transaction releases its savepoint.
+func (t *txManagerTestSuite) TestWithNestedTxSavepointRelease() {
+	t.T().Parallel()
+
+	connMock := new(ConnMock)
+	txMock := new(TxMock)
+	spMock := new(TxMock)
+
+	connMock.On("Begin", mock.Anything).Return(txMock, nil)
+	txMock.On("Begin", mock.Anything).Return(spMock, nil)
+	spMock.On("Commit", mock.Anything).Return(nil)
+	txMock.On("Commit", mock.Anything).Return(nil)
+
+	transactor := Transactor{conn: connMock}
+
+	err := transactor.WithNestedTx(context.Background(), func(ctx context.Context, tx Tx) error {
+		return transactor.WithNestedTx(ctx, func(ctx context.Context, tx Tx) error {
+			return nil
+		})
+	})
+
+	assert.NoError(t.T(), err)
+	connMock.AssertExpectations(t.T())
+	txMock.AssertExpectations(t.T())
+	spMock.AssertExpectations(t.T())
+}
+
+// TestSavepointManual tests the manual savepoint API.
+func (t *txManagerTestSuite) TestSavepointManual() {
+	t.T().Parallel()
+
+	connMock := new(ConnMock)
+	txMock := new(TxMock)
+
+	connMock.On("Begin", mock.Anything).Return(txMock, nil)
+	txMock.On("Exec", mock.Anything, `SAVEPOINT "before_import"`).Return(nil, nil)
+	txMock.On("Exec", mock.Anything, `ROLLBACK TO SAVEPOINT "before_import"`).Return(nil, nil)
+	txMock.On("Exec", mock.Anything, `RELEASE SAVEPOINT "before_import"`).Return(nil, nil)
+	txMock.On("Commit", mock.Anything).Return(nil)
+
+	transactor := Transactor{conn: connMock}
+
+	err := transactor.WithTx(context.Background(), func(ctx context.Context, tx Tx) error {
+		if err := transactor.Savepoint(ctx, "before_import"); err != nil {
+			return err
+		}
+		if err := transactor.RollbackTo(ctx, "before_import"); err != nil {
+			return err
+		}
+		return transactor.ReleaseSavepoint(ctx, "before_import")
+	})
+
+	assert.NoError(t.T(), err)
+	connMock.AssertExpectations(t.T())
+	txMock.AssertExpectations(t.T())
+}
+
+// TestSavepointNoTransaction tests that savepoints require an active transaction.
+func (t *txManagerTestSuite) TestSavepointNoTransaction() {
+	t.T().Parallel()
+
+	transactor := Transactor{conn: new(ConnMock)}
+
+	err := transactor.Savepoint(context.Background(), "sp")
+	assert.ErrorIs(t.T(), err, ErrNoTransaction)
+}
+
+// TestWithTxOptionsBeginTx tests that non-default options are passed to BeginTx.
+func (t *txManagerTestSuite) TestWithTxOptionsBeginTx() {
+	t.T().Parallel()
+
+	connMock := new(ConnMock)
+	txMock := new(TxMock)
+	opts := TxOptions{IsoLevel: Serializable, AccessMode: ReadOnly, DeferrableMode: Deferrable}
+
+	connMock.On("BeginTx", mock.Anything, opts).Return(txMock, nil)
+	txMock.On("Commit", mock.Anything).Return(nil)
+
+	transactor := Transactor{conn: connMock}
+
+	err := transactor.WithTxOptions(context.Background(), opts, func(ctx context.Context, tx Tx) error {
+		// A nested call without explicit options joins the active transaction.
+		return transactor.WithTx(ctx, func(ctx context.Context, inner Tx) error {
+			assert.Same(t.T(), tx, inner)
+			return nil
+		})
+	})
+
+	assert.NoError(t.T(), err)
+	connMock.AssertExpectations(t.T())
+	txMock.AssertExpectations(t.T())
+}
+
+// TestWithTxOptionsIncompatible tests that a nested call requesting incompatible options fails.
+func (t *txManagerTestSuite) TestWithTxOptionsIncompatible() {
+	t.T().Parallel()
+
+	connMock := new(ConnMock)
+	txMock := new(TxMock)
+	opts := TxOptions{IsoLevel: RepeatableRead, AccessMode: ReadOnly}
+
+	connMock.On("BeginTx", mock.Anything, opts).Return(txMock, nil)
+	txMock.On("Rollback", mock.Anything).Return(nil)
+
+	transactor := Transactor{conn: connMock}
+
+	err := transactor.WithTxOptions(context.Background(), opts, func(ctx context.Context, tx Tx) error {
+		called := false
+		err := transactor.WithTxOptions(ctx, TxOptions{IsoLevel: Serializable}, func(ctx context.Context, tx Tx) error {
+			called = true
+			return nil
+		})
+		assert.False(t.T(), called, "function must not run in an incompatible transaction")
+		return err
+	})
+
+	assert.ErrorIs(t.T(), err, ErrIncompatibleTxOptions)
+	connMock.AssertExpectations(t.T())
+	txMock.AssertExpectations(t.T())
+}
+
+// TestCheckTxOptions tests the compatibility rules between nested and active transaction options.
+func (t *txManagerTestSuite) TestCheckTxOptions() {
+	t.T().Parallel()
+
+	cases := []struct {
+		name   string
+		active TxOptions
+		opts   TxOptions
+		ok     bool
+	}{
+		{"empty request", TxOptions{IsoLevel: Serializable, AccessMode: ReadOnly}, TxOptions{}, true},
+		{"same isolation", TxOptions{IsoLevel: Serializable}, TxOptions{IsoLevel: Serializable}, true},
+		{"different isolation", TxOptions{}, TxOptions{IsoLevel: RepeatableRead}, false},
+		{"read only inside read write", TxOptions{}, TxOptions{AccessMode: ReadOnly}, true},
+		{"read write inside read only", TxOptions{AccessMode: ReadOnly}, TxOptions{AccessMode: ReadWrite}, false},
+		{"deferrable inside not deferrable", TxOptions{}, TxOptions{DeferrableMode: Deferrable}, false},
+	}
+
+	for _, c := range cases {
+		err := checkTxOptions(c.active, c.opts)
+		if c.ok {
+			t.NoError(err, c.name)
+		} else {
+			t.ErrorIs(err, ErrIncompatibleTxOptions, c.name)
+		}
+	}
+}
+
+// TestWithTxRetrySerializationFailure tests that the whole transaction is re-run after a serialization failure.
+func (t *txManagerTestSuite) TestWithTxRetrySerializationFailure() {
+	t.T().Parallel()
+
+	connMock := new(ConnMock)
+	firstTx := new(TxMock)
+	secondTx := new(TxMock)
+
+	connMock.On("Begin", mock.Anything).Return(firstTx, nil).Once()
+	connMock.On("Begin", mock.Anything).Return(secondTx, nil).Once()
+	firstTx.On("Rollback", mock.Anything).Return(nil)
+	secondTx.On("Commit", mock.Anything).Return(nil)
+
+	transactor := Transactor{conn: connMock}
+	WithRetryPolicy(RetryPolicy{InitialBackoff: time.Nanosecond})(&transactor)
+
+	var attempts []int
+	err := transactor.WithTx(context.Background(), func(ctx context.Context, tx Tx) error {
+		// The nested call must not retry on its own.
+		return transactor.WithTx(ctx, func(ctx context.Context, tx Tx) error {
+			attempts = append(attempts, RetryAttempt(ctx))
+			if tx == firstTx {
+				return &pgconn.PgError{Code: codeSerializationFailure}
+			}
+			return nil
+		})
+	})
+
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), []int{1, 2}, attempts)
+	connMock.AssertExpectations(t.T())
+	firstTx.AssertExpectations(t.T())
+	secondTx.AssertExpectations(t.T())
+}
+
+// TestWithTxRetryExhausted tests that the attempt count is reported once the retries are exhausted.
+func (t *txManagerTestSuite) TestWithTxRetryExhausted() {
+	t.T().Parallel()
+
+	connMock := new(ConnMock)
+	txMock := new(TxMock)
+
+	connMock.On("Begin", mock.Anything).Return(txMock, nil).Times(2)
+	txMock.On("Rollback", mock.Anything).Return(nil).Times(2)
+
+	transactor := Transactor{conn: connMock}
+	WithRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Nanosecond})(&transactor)
+
+	deadlock := &pgconn.PgError{Code: codeDeadlockDetected}
+	err := transactor.WithNestedTx(context.Background(), func(ctx context.Context, tx Tx) error {
+		return deadlock
+	})
+
+	var retryErr *RetryError
+	assert.ErrorAs(t.T(), err, &retryErr)
+	assert.Equal(t.T(), 2, retryErr.Attempts)
+	assert.ErrorIs(t.T(), err, deadlock)
+	connMock.AssertExpectations(t.T())
+	txMock.AssertExpectations(t.T())
+}
+
+// TestWithTxNoRetryOnOtherErrors tests that errors which are not retryable are returned immediately.
+func (t *txManagerTestSuite) TestWithTxNoRetryOnOtherErrors() {
+	t.T().Parallel()
+
+	connMock := new(ConnMock)
+	txMock := new(TxMock)
+
+	connMock.On("Begin", mock.Anything).Return(txMock, nil).Once()
+	txMock.On("Rollback", mock.Anything).Return(nil).Once()
+
+	transactor := Transactor{conn: connMock}
+	WithRetryPolicy(RetryPolicy{InitialBackoff: time.Nanosecond})(&transactor)
+
+	fnErr := &pgconn.PgError{Code: "23505"}
+	err := transactor.WithTx(context.Background(), func(ctx context.Context, tx Tx) error {
+		return fnErr
+	})
+
+	assert.Equal(t.T(), error(fnErr), err)
+	connMock.AssertExpectations(t.T())
+	txMock.AssertExpectations(t.T())
+}
+
+// TestOnCommitHooks tests that commit hooks run in registration order after the outermost commit,
+// and that commit hooks registered in a rolled back savepoint are discarded.
+func (t *txManagerTestSuite) TestOnCommitHooks() {
+	t.T().Parallel()
+
+	connMock := new(ConnMock)
+	txMock := new(TxMock)
+	spMock := new(TxMock)
+
+	connMock.On("Begin", mock.Anything).Return(txMock, nil)
+	txMock.On("Begin", mock.Anything).Return(spMock, nil)
+	spMock.On("Rollback", mock.Anything).Return(nil)
+
+	var calls []string
+	txMock.On("Commit", mock.Anything).Run(func(mock.Arguments) {
+		calls = append(calls, "commit")
+	}).Return(nil)
+
+	var panicked error
+	transactor := Transactor{conn: connMock}
+	WithHookPanicHandler(func(ctx context.Context, err error) {
+		panicked = err
+	})(&transactor)
+
+	err := transactor.WithNestedTx(context.Background(), func(ctx context.Context, tx Tx) error {
+		_ = OnCommit(ctx, func(context.Context) { calls = append(calls, "first") })
+		_ = OnCommit(ctx, func(context.Context) { panic("broken hook") })
+
+		_ = transactor.WithNestedTx(ctx, func(ctx context.Context, tx Tx) error {
+			_ = OnCommit(ctx, func(context.Context) { calls = append(calls, "discarded") })
+			_ = OnRollback(ctx, func(ctx context.Context, err error) { calls = append(calls, "savepoint rollback") })
+			return errors.New("error in savepoint")
+		})
+
+		return OnCommit(ctx, func(ctx context.Context) {
+			// Hooks run outside the finished transaction.
+			assert.Nil(t.T(), ctx.Value(transactor.txKey()))
+			calls = append(calls, "second")
+		})
+	})
+
+	assert.NoError(t.T(), err)
+	// The rollback hooks of the savepoint run in registration order too, once the transaction has ended.
+	assert.Equal(t.T(), []string{"commit", "first", "savepoint rollback", "second"}, calls)
+
+	var hookErr *HookPanicError
+	assert.ErrorAs(t.T(), panicked, &hookErr)
+	assert.Equal(t.T(), "broken hook", hookErr.Value)
+
+	connMock.AssertExpectations(t.T())
+	txMock.AssertExpectations(t.T())
+	spMock.AssertExpectations(t.T())
+}
+
+// TestOnRollbackHooks tests that rollback hooks receive the error that caused the rollback.
+func (t *txManagerTestSuite) TestOnRollbackHooks() {
+	t.T().Parallel()
+
+	connMock := new(ConnMock)
+	txMock := new(TxMock)
+
+	connMock.On("Begin", mock.Anything).Return(txMock, nil)
+	txMock.On("Rollback", mock.Anything).Return(nil)
+
+	transactor := Transactor{conn: connMock}
+	fnErr := errors.New("error in transaction")
+
+	var (
+		committed  bool
+		rolledBack error
+	)
+	err := transactor.WithTx(context.Background(), func(ctx context.Context, tx Tx) error {
+		_ = OnCommit(ctx, func(context.Context) { committed = true })
+		_ = OnRollback(ctx, func(ctx context.Context, err error) { rolledBack = err })
+		return fnErr
+	})
+
+	assert.ErrorIs(t.T(), err, fnErr)
+	assert.False(t.T(), committed)
+	assert.ErrorIs(t.T(), rolledBack, fnErr)
+	assert.ErrorIs(t.T(), OnCommit(context.Background(), func(context.Context) {}), ErrNoTransaction)
+	connMock.AssertExpectations(t.T())
+	txMock.AssertExpectations(t.T())
+}
+
+// TestOnRollbackHooksSavepoint tests that the rollback hooks of a rolled back savepoint run with its error
+// only once the outermost transaction has ended.
+func (t *txManagerTestSuite) TestOnRollbackHooksSavepoint() {
+	t.T().Parallel()
+
+	connMock := new(ConnMock)
+	txMock := new(TxMock)
+	spMock := new(TxMock)
+
+	connMock.On("Begin", mock.Anything).Return(txMock, nil)
+	txMock.On("Begin", mock.Anything).Return(spMock, nil)
+	spMock.On("Rollback", mock.Anything).Return(nil)
+	txMock.On("Rollback", mock.Anything).Return(nil)
+
+	transactor := Transactor{conn: connMock}
+	spErr := errors.New("error in savepoint")
+	fnErr := errors.New("error in transaction")
+
+	var calls []string
+	var spRolledBack error
+	err := transactor.WithNestedTx(context.Background(), func(ctx context.Context, tx Tx) error {
+		_ = transactor.WithNestedTx(ctx, func(ctx context.Context, tx Tx) error {
+			_ = OnRollback(ctx, func(ctx context.Context, err error) {
+				spRolledBack = err
+				calls = append(calls, "savepoint rollback")
+			})
+			return spErr
+		})
+		assert.Empty(t.T(), calls, "the transaction is still open")
+
+		_ = OnRollback(ctx, func(ctx context.Context, err error) { calls = append(calls, "rollback") })
+		return fnErr
+	})
+
+	assert.ErrorIs(t.T(), err, fnErr)
+	assert.Equal(t.T(), []string{"savepoint rollback", "rollback"}, calls)
+	assert.ErrorIs(t.T(), spRolledBack, spErr)
+	connMock.AssertExpectations(t.T())
+	txMock.AssertExpectations(t.T())
+	spMock.AssertExpectations(t.T())
+}
+
+// TestWithReadOnlyTxReplica tests that read only transactions run on the replica
+// and reject nested read write transactions.
+func (t *txManagerTestSuite) TestWithReadOnlyTxReplica() {
+	t.T().Parallel()
+
+	masterMock := new(ConnMock)
+	replicaMock := new(ConnMock)
+	txMock := new(TxMock)
+
+	replicaMock.On("BeginTx", mock.Anything, TxOptions{AccessMode: ReadOnly}).Return(txMock, nil)
+	txMock.On("Commit", mock.Anything).Return(nil)
+
+	transactor := Transactor{conn: masterMock, replica: func() (Conn, int) { return replicaMock, 1 }}
+
+	err := transactor.WithReadOnlyTx(context.Background(), func(ctx context.Context, tx Tx) error {
+		assert.ErrorIs(t.T(), transactor.WithTx(ctx, func(ctx context.Context, tx Tx) error {
+			return nil
+		}), ErrReadOnlyTx)
+		assert.ErrorIs(t.T(), transactor.WithNestedTx(ctx, func(ctx context.Context, tx Tx) error {
+			return nil
+		}), ErrReadOnlyTx)
+
+		// Read only work may still be nested.
+		return transactor.WithReadOnlyTx(ctx, func(ctx context.Context, tx Tx) error {
+			return nil
+		})
+	})
+
+	assert.NoError(t.T(), err)
+	masterMock.AssertExpectations(t.T())
+	replicaMock.AssertExpectations(t.T())
+	txMock.AssertExpectations(t.T())
+}
+
+// TestWithReadOnlyTxFallback tests the fallback to master when there is no replica.
+func (t *txManagerTestSuite) TestWithReadOnlyTxFallback() {
+	t.T().Parallel()
+
+	masterMock := new(ConnMock)
+	txMock := new(TxMock)
+
+	masterMock.On("BeginTx", mock.Anything, TxOptions{AccessMode: ReadOnly}).Return(txMock, nil)
+	txMock.On("Commit", mock.Anything).Return(nil)
+
+	transactor := Transactor{conn: masterMock, replicaFallback: true}
+
+	err := transactor.WithReadOnlyTx(context.Background(), func(ctx context.Context, tx Tx) error {
+		return nil
+	})
+	assert.NoError(t.T(), err)
+
+	WithReplicaFallback(false)(&transactor)
+	err = transactor.WithReadOnlyTx(context.Background(), func(ctx context.Context, tx Tx) error {
+		return nil
+	})
+	assert.ErrorIs(t.T(), err, ErrNoReplica)
+
+	masterMock.AssertExpectations(t.T())
+	txMock.AssertExpectations(t.T())
+}
+
+// TestQuerier tests that the querier resolves to the transaction in the context or to the pool.
+func (t *txManagerTestSuite) TestQuerier() {
+	t.T().Parallel()
+
+	connMock := new(ConnMock)
+	txMock := new(TxMock)
+
+	connMock.On("Begin", mock.Anything).Return(txMock, nil)
+	connMock.On("Exec", mock.Anything, "DELETE FROM sessions").Return(nil, nil)
+	txMock.On("Exec", mock.Anything, "UPDATE users SET active = false").Return(nil, nil)
+	txMock.On("Commit", mock.Anything).Return(nil)
+
+	transactor := Transactor{conn: connMock}
+	ctx := context.Background()
+
+	// Outside a transaction, the query goes to the pool.
+	_, err := transactor.Querier(ctx).Exec(ctx, "DELETE FROM sessions")
+	assert.NoError(t.T(), err)
+
+	err = transactor.WithTx(ctx, func(ctx context.Context, tx Tx) error {
+		_, err := transactor.Querier(ctx).Exec(ctx, "UPDATE users SET active = false")
+		return err
+	})
+
+	assert.NoError(t.T(), err)
+	connMock.AssertExpectations(t.T())
+	txMock.AssertExpectations(t.T())
+}
+
+// TestTxManagersNamespaced tests that a manager never joins a transaction of a manager for another pool.
+func (t *txManagerTestSuite) TestTxManagersNamespaced() {
+	t.T().Parallel()
+
+	billingConn := new(ConnMock)
+	defaultConn := new(ConnMock)
+	billingTx := new(TxMock)
+	defaultTx := new(TxMock)
+
+	billingConn.On("Begin", mock.Anything).Return(billingTx, nil).Once()
+	defaultConn.On("Begin", mock.Anything).Return(defaultTx, nil).Once()
+	billingTx.On("Commit", mock.Anything).Return(nil)
+	defaultTx.On("Commit", mock.Anything).Return(nil)
+
+	billing := NewTxManagerFromConn(billingConn)
+	sameBilling := NewTxManagerFromConn(billingConn)
+	defaultManager := NewTxManagerFromConn(defaultConn)
+
+	err := billing.WithTx(context.Background(), func(ctx context.Context, tx Tx) error {
+		// A manager for the same pool joins the transaction.
+		if err := sameBilling.WithTx(ctx, func(ctx context.Context, inner Tx) error {
+			assert.Same(t.T(), billingTx, inner)
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		// A manager for another pool starts its own transaction.
+		return defaultManager.WithTx(ctx, func(ctx context.Context, inner Tx) error {
+			assert.Same(t.T(), defaultTx, inner)
+			assert.Same(t.T(), billingTx, billing.Querier(ctx))
+			return nil
+		})
+	})
+
+	assert.NoError(t.T(), err)
+	billingConn.AssertExpectations(t.T())
+	defaultConn.AssertExpectations(t.T())
+	billingTx.AssertExpectations(t.T())
+	defaultTx.AssertExpectations(t.T())
+}
+
+// TestTxManagersStateNamespaced tests that the hooks, unit of work and depth of a transaction
+// stay with the manager that started it, when nested into the transaction of another manager.
+func (t *txManagerTestSuite) TestTxManagersStateNamespaced() {
+	t.T().Parallel()
+
+	billingConn := new(ConnMock)
+	defaultConn := new(ConnMock)
+	billingTx := new(TxMock)
+	defaultTx := new(TxMock)
+	results := &fakeBatchResults{tags: []pgconn.CommandTag{pgconn.CommandTag("INSERT 0 1")}, errs: []error{nil}}
+
+	billingConn.On("Begin", mock.Anything).Return(billingTx, nil).Once()
+	defaultConn.On("Begin", mock.Anything).Return(defaultTx, nil).Once()
+	defaultTx.On("SendBatch", mock.Anything, mock.MatchedBy(func(b *pgx.Batch) bool {
+		return b.Len() == 1
+	})).Return(results).Once()
+	defaultTx.On("Commit", mock.Anything).Return(nil)
+	billingTx.On("Rollback", mock.Anything).Return(nil)
+
+	billing := NewTxManagerFromConn(billingConn)
+	defaultManager := NewTxManagerFromConn(defaultConn)
+
+	var calls []string
+	billingErr := errors.New("billing failed")
+	err := billing.WithTx(context.Background(), func(ctx context.Context, tx Tx) error {
+		_ = OnRollback(ctx, func(context.Context, error) { calls = append(calls, "billing rollback") })
+
+		if err := defaultManager.WithTx(ctx, func(ctx context.Context, tx Tx) error {
+			assert.Equal(t.T(), 1, TxDepth(ctx))
+			_ = Queue(ctx, "INSERT INTO audit DEFAULT VALUES")
+			return OnCommit(ctx, func(context.Context) { calls = append(calls, "default commit") })
+		}); err != nil {
+			return err
+		}
+
+		assert.Equal(t.T(), 1, TxDepth(ctx))
+		return billingErr
+	})
+
+	assert.ErrorIs(t.T(), err, billingErr)
+	assert.Equal(t.T(), []string{"default commit", "billing rollback"}, calls)
+	billingConn.AssertExpectations(t.T())
+	defaultConn.AssertExpectations(t.T())
+	billingTx.AssertExpectations(t.T())
+	defaultTx.AssertExpectations(t.T())
+	assert.Equal(t.T(), 1, results.next)
+}
+
+// TestWithTxTimeouts tests that the timeouts are set right after begin, with per call overrides.
+func (t *txManagerTestSuite) TestWithTxTimeouts() {
+	t.T().Parallel()
+
+	connMock := new(ConnMock)
+	txMock := new(TxMock)
+
+	connMock.On("Begin", mock.Anything).Return(txMock, nil)
+	txMock.On("Exec", mock.Anything, "SET LOCAL statement_timeout = 5000; SET LOCAL lock_timeout = 1000").Return(nil, nil).Once()
+	txMock.On("Exec", mock.Anything, "SET LOCAL statement_timeout = 5000; SET LOCAL lock_timeout = 200").Return(nil, nil).Once()
+	txMock.On("Exec", mock.Anything, "SET LOCAL statement_timeout = 5000; SET LOCAL lock_timeout = 1").Return(nil, nil).Once()
+	txMock.On("Commit", mock.Anything).Return(nil)
+
+	transactor := Transactor{conn: connMock}
+	WithTimeouts(Timeouts{Statement: 5 * time.Second, Lock: time.Second})(&transactor)
+
+	fn := func(ctx context.Context, tx Tx) error {
+		// Joining an ongoing transaction does not set the timeouts again.
+		return transactor.WithTx(ContextWithTimeouts(ctx, Timeouts{Lock: time.Minute}), func(ctx context.Context, tx Tx) error {
+			return nil
+		})
+	}
+
+	assert.NoError(t.T(), transactor.WithTx(context.Background(), fn))
+	assert.NoError(t.T(), transactor.WithTx(ContextWithTimeouts(context.Background(), Timeouts{Lock: 200 * time.Millisecond}), fn))
+	// A sub-millisecond timeout is rounded up rather than disabled.
+	assert.NoError(t.T(), transactor.WithTx(ContextWithTimeouts(context.Background(), Timeouts{Lock: 10 * time.Microsecond}), fn))
+	connMock.AssertExpectations(t.T())
+	txMock.AssertExpectations(t.T())
+}
+
+// TestTracer tests that transactions and savepoints are reported to the tracer with their depth and outcome.
+func (t *txManagerTestSuite) TestTracer() {
+	t.T().Parallel()
+
+	connMock := new(ConnMock)
+	txMock := new(TxMock)
+	spMock := new(TxMock)
+	opts := TxOptions{IsoLevel: Serializable}
+
+	connMock.On("BeginTx", mock.Anything, opts).Return(txMock, nil)
+	txMock.On("Begin", mock.Anything).Return(spMock, nil)
+	spMock.On("Rollback", mock.Anything).Return(nil)
+	txMock.On("Commit", mock.Anything).Return(nil)
+
+	tracer := new(recordingTracer)
+	transactor := Transactor{conn: connMock, name: "billing"}
+	WithTracer(tracer)(&transactor)
+
+	spErr := errors.New("error in savepoint")
+	err := transactor.WithTxOptions(context.Background(), opts, func(ctx context.Context, tx Tx) error {
+		_ = transactor.WithNestedTx(ctx, func(ctx context.Context, tx Tx) error {
+			return spErr
+		})
+		return nil
+	})
+
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), 2, len(tracer.starts))
+	assert.Equal(t.T(), pgxpool.TraceTxStartData{
+		Pool:      "billing",
+		Node:      0,
+		Depth:     1,
+		Options:   opts,
+		StartTime: tracer.starts[0].StartTime,
+	}, tracer.starts[0])
+	assert.Equal(t.T(), 2, tracer.starts[1].Depth)
+
+	// The savepoint ends first, rolled back with its error.
+	assert.Equal(t.T(), 2, len(tracer.ends))
+	assert.Equal(t.T(), 2, tracer.ends[0].Depth)
+	assert.False(t.T(), tracer.ends[0].Committed)
+	assert.ErrorIs(t.T(), tracer.ends[0].Err, spErr)
+	assert.Equal(t.T(), 1, tracer.ends[1].Depth)
+	assert.True(t.T(), tracer.ends[1].Committed)
+	assert.NoError(t.T(), tracer.ends[1].Err)
+
+	connMock.AssertExpectations(t.T())
+	txMock.AssertExpectations(t.T())
+	spMock.AssertExpectations(t.T())
+}
+
+// TestWithAdvisoryLock tests that the lock is taken in a new transaction before the function runs.
+func (t *txManagerTestSuite) TestWithAdvisoryLock() {
+	t.T().Parallel()
+
+	connMock := new(ConnMock)
+	txMock := new(TxMock)
+
+	connMock.On("Begin", mock.Anything).Return(txMock, nil)
+	txMock.On("Exec", mock.Anything, "SELECT pg_advisory_xact_lock($1)", LockKey("customer:42")).Return(nil, nil)
+	txMock.On("Commit", mock.Anything).Return(nil)
+
+	transactor := Transactor{conn: connMock}
+
+	called := false
+	err := transactor.WithAdvisoryLock(context.Background(), AdvisoryLock{Key: LockKey("customer:42")}, func(ctx context.Context, tx Tx) error {
+		called = true
+		return nil
+	})
+
+	assert.NoError(t.T(), err)
+	assert.True(t.T(), called)
+	assert.NotEqual(t.T(), LockKey("customer:42"), LockKey("customer:43"))
+	connMock.AssertExpectations(t.T())
+	txMock.AssertExpectations(t.T())
+}
+
+// TestWithAdvisoryLockNotAcquired tests that a try-lock held by another transaction
+// fails with ErrLockNotAcquired once its timeout expires, without running the function.
+func (t *txManagerTestSuite) TestWithAdvisoryLockNotAcquired() {
+	t.T().Parallel()
+
+	connMock := new(ConnMock)
+	txMock := new(TxMock)
+	rowMock := new(RowMock)
+
+	connMock.On("Begin", mock.Anything).Return(txMock, nil)
+	txMock.On("QueryRow", mock.Anything, "SELECT pg_try_advisory_xact_lock($1)", int64(7)).Return(rowMock)
+	rowMock.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(0).(*bool) = false
+	}).Return(nil)
+	txMock.On("Rollback", mock.Anything).Return(nil)
+
+	transactor := Transactor{conn: connMock}
+
+	err := transactor.WithAdvisoryLock(context.Background(), AdvisoryLock{Key: 7, Try: true, Timeout: 20 * time.Millisecond}, func(ctx context.Context, tx Tx) error {
+		t.T().Fatal("the function must not run without the lock")
+		return nil
+	})
+
+	assert.ErrorIs(t.T(), err, ErrLockNotAcquired)
+	assert.Greater(t.T(), len(rowMock.Calls), 1)
+	connMock.AssertExpectations(t.T())
+	txMock.AssertExpectations(t.T())
+}
+
+// TestWithAdvisoryLockNested tests that inside WithNestedTx the lock is taken in the savepoint.
+func (t *txManagerTestSuite) TestWithAdvisoryLockNested() {
+	t.T().Parallel()
+
+	connMock := new(ConnMock)
+	txMock := new(TxMock)
+	spMock := new(TxMock)
+	rowMock := new(RowMock)
+
+	connMock.On("Begin", mock.Anything).Return(txMock, nil)
+	txMock.On("Begin", mock.Anything).Return(spMock, nil)
+	spMock.On("QueryRow", mock.Anything, "SELECT pg_try_advisory_xact_lock($1)", int64(7)).Return(rowMock)
+	rowMock.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(0).(*bool) = true
+	}).Return(nil)
+	spMock.On("Commit", mock.Anything).Return(nil)
+	txMock.On("Commit", mock.Anything).Return(nil)
+
+	transactor := Transactor{conn: connMock}
+
+	err := transactor.WithTx(context.Background(), func(ctx context.Context, tx Tx) error {
+		return transactor.WithNestedTx(ctx, func(ctx context.Context, sp Tx) error {
+			return transactor.WithAdvisoryLock(ctx, AdvisoryLock{Key: 7, Try: true}, func(ctx context.Context, tx Tx) error {
+				assert.Equal(t.T(), sp, tx)
+				return nil
+			})
+		})
+	})
+
+	assert.NoError(t.T(), err)
+	connMock.AssertExpectations(t.T())
+	txMock.AssertExpectations(t.T())
+	spMock.AssertExpectations(t.T())
+	rowMock.AssertExpectations(t.T())
+}
+
+// TestTxIntrospection tests InTx, TxDepth, TxFromContext and the lazily fetched transaction id.
+func (t *txManagerTestSuite) TestTxIntrospection() {
+	t.T().Parallel()
+
+	connMock := new(ConnMock)
+	txMock := new(TxMock)
+	spMock := new(TxMock)
+	rowMock := new(RowMock)
+	started := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	connMock.On("Begin", mock.Anything).Return(txMock, nil)
+	txMock.On("Begin", mock.Anything).Return(spMock, nil)
+	spMock.On("QueryRow", mock.Anything, "SELECT txid_current(), now()").Return(rowMock).Once()
+	rowMock.On("Scan", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(0).(*int64) = 42
+		*args.Get(1).(*time.Time) = started
+	}).Return(nil).Once()
+	spMock.On("Commit", mock.Anything).Return(nil)
+	txMock.On("Commit", mock.Anything).Return(nil)
+
+	transactor := Transactor{conn: connMock}
+
+	ctx := context.Background()
+	assert.False(t.T(), InTx(ctx))
+	assert.Equal(t.T(), 0, TxDepth(ctx))
+	_, err := TxID(ctx)
+	assert.ErrorIs(t.T(), err, ErrNoTransaction)
+	assert.NotPanics(t.T(), func() { MustNotBeInTx(ctx) })
+
+	err = transactor.WithTx(ctx, func(ctx context.Context, tx Tx) error {
+		assert.True(t.T(), InTx(ctx))
+		assert.Equal(t.T(), 1, TxDepth(ctx))
+		current, _ := TxFromContext(ctx)
+		assert.Equal(t.T(), txMock, current)
+
+		return transactor.WithNestedTx(ctx, func(ctx context.Context, tx Tx) error {
+			assert.Equal(t.T(), 2, TxDepth(ctx))
+			current, _ := TxFromContext(ctx)
+			assert.Equal(t.T(), spMock, current)
+
+			id, err := TxID(ctx)
+			assert.NoError(t.T(), err)
+			assert.Equal(t.T(), int64(42), id)
+			start, err := TxStartTime(ctx)
+			assert.NoError(t.T(), err)
+			assert.Equal(t.T(), started, start)
+			return nil
+		})
+	})
+
+	assert.NoError(t.T(), err)
+	connMock.AssertExpectations(t.T())
+	txMock.AssertExpectations(t.T())
+	spMock.AssertExpectations(t.T())
+	rowMock.AssertExpectations(t.T())
+}
+
+// TestMustNotBeInTx tests that the guard panics inside a transaction only in development mode.
+func (t *txManagerTestSuite) TestMustNotBeInTx() {
+	connMock := new(ConnMock)
+	txMock := new(TxMock)
+
+	connMock.On("Begin", mock.Anything).Return(txMock, nil)
+	txMock.On("Commit", mock.Anything).Return(nil)
+
+	transactor := Transactor{conn: connMock}
+
+	err := transactor.WithTx(context.Background(), func(ctx context.Context, tx Tx) error {
+		assert.NotPanics(t.T(), func() { MustNotBeInTx(ctx) })
+
+		SetDevMode(true)
+		defer SetDevMode(false)
+		assert.Panics(t.T(), func() { MustNotBeInTx(ctx) })
+		return nil
+	})
+
+	assert.NoError(t.T(), err)
+}
+
+// poolConnMock is a ConnMock able to hand out separate sessions, as a pool does.
+type poolConnMock struct {
+	*ConnMock
+}
+
+func (c poolConnMock) Acquire(context.Context) (*jackcpool.Conn, error) {
+	return nil, errors.New("not implemented")
+}
+
+// TestWithPropagationRequiresNew tests that a new transaction is started and committed
+// on its own while the outer one is suspended.
+func (t *txManagerTestSuite) TestWithPropagationRequiresNew() {
+	t.T().Parallel()
+
+	connMock := new(ConnMock)
+	outerTx := new(TxMock)
+	innerTx := new(TxMock)
+
+	connMock.On("Begin", mock.Anything).Return(outerTx, nil).Once()
+	connMock.On("Begin", mock.Anything).Return(innerTx, nil).Once()
+	innerTx.On("Commit", mock.Anything).Return(nil)
+	outerTx.On("Rollback", mock.Anything).Return(nil)
+
+	transactor := Transactor{conn: poolConnMock{connMock}}
+	outerErr := errors.New("outer failed")
+
+	var innerCommitted bool
+	err := transactor.WithTx(context.Background(), func(ctx context.Context, tx Tx) error {
+		err := transactor.WithPropagation(ctx, PropagationRequiresNew, func(ctx context.Context, tx Tx) error {
+			assert.Equal(t.T(), innerTx, tx)
+			assert.Equal(t.T(), 1, TxDepth(ctx))
+			return OnCommit(ctx, func(context.Context) { innerCommitted = true })
+		})
+		assert.NoError(t.T(), err)
+		// The commit hooks of the new transaction run as soon as it commits.
+		assert.True(t.T(), innerCommitted)
+		assert.Equal(t.T(), outerTx, transactor.Querier(ctx))
+		return outerErr
+	})
+
+	assert.ErrorIs(t.T(), err, outerErr)
+	connMock.AssertExpectations(t.T())
+	outerTx.AssertExpectations(t.T())
+	innerTx.AssertExpectations(t.T())
+}
+
+// TestWithPropagationRequiresNewWithoutPool tests that no transaction is started on a connection
+// that cannot hand out another session.
+func (t *txManagerTestSuite) TestWithPropagationRequiresNewWithoutPool() {
+	t.T().Parallel()
+
+	connMock := new(ConnMock)
+	txMock := new(TxMock)
+
+	connMock.On("Begin", mock.Anything).Return(txMock, nil).Once()
+	txMock.On("Rollback", mock.Anything).Return(nil)
+
+	transactor := NewTxManagerFromConn(connMock)
+
+	err := transactor.WithTx(context.Background(), func(ctx context.Context, tx Tx) error {
+		return transactor.WithPropagation(ctx, PropagationRequiresNew, func(ctx context.Context, tx Tx) error {
+			t.T().Fatal("the function must not run")
+			return nil
+		})
+	})
+
+	assert.ErrorIs(t.T(), err, ErrRequiresNewUnsupported)
+	connMock.AssertExpectations(t.T())
+	txMock.AssertExpectations(t.T())
+}
+
+// TestWithPropagationChecks tests the modes that depend on whether a transaction is in the context.
+func (t *txManagerTestSuite) TestWithPropagationChecks() {
+	t.T().Parallel()
+
+	connMock := new(ConnMock)
+	txMock := new(TxMock)
+
+	connMock.On("Begin", mock.Anything).Return(txMock, nil).Once()
+	txMock.On("Commit", mock.Anything).Return(nil)
+
+	transactor := Transactor{conn: connMock}
+	fail := func(ctx context.Context, tx Tx) error {
+		t.T().Fatal("the function must not run")
+		return nil
+	}
+	ctx := context.Background()
+
+	assert.ErrorIs(t.T(), transactor.WithPropagation(ctx, PropagationMandatory, fail), ErrTxRequired)
+	assert.ErrorIs(t.T(), transactor.WithPropagation(ctx, Propagation(42), fail), ErrUnknownPropagation)
+	assert.NoError(t.T(), transactor.WithPropagation(ctx, PropagationSupports, func(ctx context.Context, tx Tx) error {
+		assert.Nil(t.T(), tx)
+		return nil
+	}))
+
+	err := transactor.WithTx(ctx, func(ctx context.Context, tx Tx) error {
+		assert.ErrorIs(t.T(), transactor.WithPropagation(ctx, PropagationNever, fail), ErrTxNotAllowed)
+		for _, propagation := range []Propagation{PropagationMandatory, PropagationSupports, PropagationRequired} {
+			assert.NoError(t.T(), transactor.WithPropagation(ctx, propagation, func(ctx context.Context, joined Tx) error {
+				assert.Equal(t.T(), tx, joined)
+				return nil
+			}), propagation.String())
+		}
+		return nil
+	})
+
+	assert.NoError(t.T(), err)
+	connMock.AssertExpectations(t.T())
+	txMock.AssertExpectations(t.T())
+}
+
+// TestWithTxCommitFailed tests that a failed commit is reported wrapped into ErrCommitFailed.
+func (t *txManagerTestSuite) TestWithTxCommitFailed() {
+	t.T().Parallel()
+
+	connMock := new(ConnMock)
+	txMock := new(TxMock)
+	commitErr := errors.New("connection reset")
+
+	connMock.On("Begin", mock.Anything).Return(txMock, nil)
+	txMock.On("Commit", mock.Anything).Return(commitErr)
+
+	transactor := Transactor{conn: connMock}
+
+	err := transactor.WithTx(context.Background(), func(ctx context.Context, tx Tx) error {
+		return nil
+	})
+
+	assert.ErrorIs(t.T(), err, ErrCommitFailed)
+	assert.ErrorIs(t.T(), err, commitErr)
+	connMock.AssertExpectations(t.T())
+	txMock.AssertExpectations(t.T())
+}
+
+// TestWithTxRollbackFailed tests that the rollback runs on a live context after the caller's one
+// has been canceled, and that its failure is joined to the error of the function.
+func (t *txManagerTestSuite) TestWithTxRollbackFailed() {
+	t.T().Parallel()
+
+	connMock := new(ConnMock)
+	txMock := new(TxMock)
+	rollbackErr := errors.New("connection reset")
+
+	connMock.On("Begin", mock.Anything).Return(txMock, nil)
+	txMock.On("Rollback", mock.MatchedBy(func(ctx context.Context) bool {
+		_, ok := ctx.Deadline()
+		return ctx.Err() == nil && ok
+	})).Return(rollbackErr)
+
+	transactor := Transactor{conn: connMock}
+	WithRollbackTimeout(time.Second)(&transactor)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := transactor.WithTx(ctx, func(ctx context.Context, tx Tx) error {
+		cancel()
+		return ctx.Err()
+	})
+
+	assert.ErrorIs(t.T(), err, context.Canceled)
+	assert.ErrorIs(t.T(), err, ErrRollbackFailed)
+	assert.ErrorIs(t.T(), err, rollbackErr)
+	connMock.AssertExpectations(t.T())
+	txMock.AssertExpectations(t.T())
+}
+
+// TestWithTxPanicRecovery tests that a panic is returned as a PanicError once the transaction is rolled back.
+func (t *txManagerTestSuite) TestWithTxPanicRecovery() {
+	t.T().Parallel()
+
+	connMock := new(ConnMock)
+	txMock := new(TxMock)
+	spMock := new(TxMock)
+
+	connMock.On("Begin", mock.Anything).Return(txMock, nil)
+	txMock.On("Begin", mock.Anything).Return(spMock, nil)
+	spMock.On("Rollback", mock.Anything).Return(nil)
+	txMock.On("Commit", mock.Anything).Return(nil)
+
+	transactor := Transactor{conn: connMock}
+	WithPanicRecovery()(&transactor)
+
+	var spErr error
+	err := transactor.WithTx(context.Background(), func(ctx context.Context, tx Tx) error {
+		spErr = transactor.WithNestedTx(ctx, func(ctx context.Context, tx Tx) error {
+			panic("boom")
+		})
+		return nil
+	})
+
+	assert.NoError(t.T(), err)
+	var panicErr *PanicError
+	if assert.ErrorAs(t.T(), spErr, &panicErr) {
+		assert.Equal(t.T(), "boom", panicErr.Value)
+		assert.Contains(t.T(), string(panicErr.Stack), "TestWithTxPanicRecovery")
+	}
+	connMock.AssertExpectations(t.T())
+	txMock.AssertExpectations(t.T())
+	spMock.AssertExpectations(t.T())
+}
+
+// TestUnitOfWork tests that the queued statements are sent as one batch right before the commit.
+func (t *txManagerTestSuite) TestUnitOfWork() {
+	t.T().Parallel()
+
+	connMock := new(ConnMock)
+	txMock := new(TxMock)
+	results := &fakeBatchResults{
+		tags: []pgconn.CommandTag{pgconn.CommandTag("INSERT 0 1"), pgconn.CommandTag("UPDATE 3")},
+		errs: []error{nil, nil},
+	}
+
+	connMock.On("Begin", mock.Anything).Return(txMock, nil)
+	txMock.On("SendBatch", mock.Anything, mock.MatchedBy(func(b *pgx.Batch) bool {
+		return b.Len() == 2
+	})).Return(results).Once()
+	txMock.On("Commit", mock.Anything).Return(nil)
+
+	transactor := Transactor{conn: connMock}
+
+	var updated int64
+	err := transactor.WithTx(context.Background(), func(ctx context.Context, tx Tx) error {
+		if err := Queue(ctx, "INSERT INTO users (name) VALUES ($1)", "John Doe"); err != nil {
+			return err
+		}
+		return QueueWithResult(ctx, func(tag pgconn.CommandTag, err error) error {
+			updated = tag.RowsAffected()
+			return err
+		}, "UPDATE counters SET value = value + 1")
+	})
+
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), int64(3), updated)
+	assert.ErrorIs(t.T(), Queue(context.Background(), "SELECT 1"), ErrNoTransaction)
+	connMock.AssertExpectations(t.T())
+	txMock.AssertExpectations(t.T())
+}
+
+// TestUnitOfWorkSavepoint tests that the statements queued before a savepoint are sent before it starts,
+// and that those queued in a savepoint that fails are discarded.
+func (t *txManagerTestSuite) TestUnitOfWorkSavepoint() {
+	t.T().Parallel()
+
+	connMock := new(ConnMock)
+	txMock := new(TxMock)
+	spMock := new(TxMock)
+	spErr := errors.New("error in savepoint")
+
+	connMock.On("Begin", mock.Anything).Return(txMock, nil)
+	txMock.On("SendBatch", mock.Anything, mock.Anything).
+		Return(&fakeBatchResults{tags: []pgconn.CommandTag{nil}, errs: []error{nil}}).Once()
+	txMock.On("Begin", mock.Anything).Return(spMock, nil)
+	spMock.On("Rollback", mock.Anything).Return(nil)
+	txMock.On("Commit", mock.Anything).Return(nil)
+
+	transactor := Transactor{conn: connMock}
+
+	err := transactor.WithTx(context.Background(), func(ctx context.Context, tx Tx) error {
+		assert.NoError(t.T(), Queue(ctx, "INSERT INTO users (name) VALUES ($1)", "John Doe"))
+
+		err := transactor.WithNestedTx(ctx, func(ctx context.Context, tx Tx) error {
+			assert.NoError(t.T(), Queue(ctx, "DELETE FROM users"))
+			return spErr
+		})
+		assert.ErrorIs(t.T(), err, spErr)
+		return nil
+	})
+
+	assert.NoError(t.T(), err)
+	connMock.AssertExpectations(t.T())
+	txMock.AssertExpectations(t.T())
+	spMock.AssertExpectations(t.T())
+	spMock.AssertNotCalled(t.T(), "SendBatch", mock.Anything, mock.Anything)
+}
+
+// TestNotify tests that a notification sent inside WithTx goes through the transaction,
+// so that it is delivered on commit, and through the pool outside of it.
+func (t *txManagerTestSuite) TestNotify() {
+	t.T().Parallel()
+
+	connMock := new(ConnMock)
+	txMock := new(TxMock)
+
+	connMock.On("Exec", mock.Anything, "SELECT pg_notify($1, $2)", "orders", "outside").Return(nil, nil)
+	connMock.On("Begin", mock.Anything).Return(txMock, nil)
+	txMock.On("Exec", mock.Anything, "SELECT pg_notify($1, $2)", "orders", "inside").Return(nil, nil)
+	txMock.On("Commit", mock.Anything).Return(nil)
+
+	transactor := Transactor{conn: connMock}
+
+	err := transactor.Notify(context.Background(), "orders", "outside")
+	assert.NoError(t.T(), err)
+
+	err = transactor.WithTx(context.Background(), func(ctx context.Context, tx Tx) error {
+		return transactor.Notify(ctx, "orders", "inside")
+	})
+
+	assert.NoError(t.T(), err)
+	connMock.AssertExpectations(t.T())
+	txMock.AssertExpectations(t.T())
+}
+
 func TestTxManager_Run(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,78 @@
+package pgx
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// TxOptions is an alias to pgx.TxOptions
+type TxOptions = pgx.TxOptions
+
+// Transaction isolation levels
+const (
+	Serializable    = pgx.Serializable
+	RepeatableRead  = pgx.RepeatableRead
+	ReadCommitted   = pgx.ReadCommitted
+	ReadUncommitted = pgx.ReadUncommitted
+)
+
+// Transaction access modes
+const (
+	ReadWrite = pgx.ReadWrite
+	ReadOnly  = pgx.ReadOnly
+)
+
+// Transaction deferrable modes
+const (
+	Deferrable    = pgx.Deferrable
+	NotDeferrable = pgx.NotDeferrable
+)
+
+// txContextOptionsKey is used for storing the options of the active transaction in the context.
+type txContextOptionsKey struct {
+	conn Conn
+}
+
+var (
+	// ErrIncompatibleTxOptions is the error used when the requested transaction options
+	// cannot be satisfied by the transaction already open in the context.
+	ErrIncompatibleTxOptions = errors.New("transaction options are incompatible with the active transaction")
+)
+
+// txOptionsKey returns the key the options of the active transaction are stored under in the context.
+func (t *Transactor) txOptionsKey() txContextOptionsKey {
+	return txContextOptionsKey{conn: t.conn}
+}
+
+// checkTxOptions verifies that a call requesting opts can join a transaction started with active.
+// Options left empty in the request are compatible with any active transaction.
+func checkTxOptions(active, opts TxOptions) error {
+	if opts.IsoLevel != "" && opts.IsoLevel != active.IsoLevel {
+		return fmt.Errorf("%w: isolation level %q requested, active transaction uses %q",
+			ErrIncompatibleTxOptions, opts.IsoLevel, orDefault(string(active.IsoLevel)))
+	}
+
+	// A read only transaction can not host a read write unit of work,
+	// while read only work may run inside a read write transaction.
+	if opts.AccessMode == ReadWrite && active.AccessMode == ReadOnly {
+		return fmt.Errorf("%w: %s requested, active transaction is %s",
+			ErrIncompatibleTxOptions, ReadWrite, ReadOnly)
+	}
+
+	if opts.DeferrableMode == Deferrable && active.DeferrableMode != Deferrable {
+		return fmt.Errorf("%w: %s requested, active transaction is %s",
+			ErrIncompatibleTxOptions, Deferrable, orDefault(string(active.DeferrableMode)))
+	}
+
+	return nil
+}
+
+// orDefault returns "default" for options that were not set explicitly.
+func orDefault(v string) string {
+	if v == "" {
+		return "default"
+	}
+	return v
+}
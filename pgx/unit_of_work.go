@@ -0,0 +1,138 @@
+package pgx
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// queuedStatement is a statement waiting in the unit of work of a transaction.
+type queuedStatement struct {
+	sql      string
+	args     []any
+	onResult func(pgconn.CommandTag, error) error
+}
+
+// txUnit is the unit of work of a transaction or savepoint: the statements queued to be sent with it.
+type txUnit struct {
+	sync.Mutex
+	tx    Tx
+	queue []queuedStatement
+}
+
+// BatchStatement is a statement of a batch sent by the unit of work of a transaction.
+type BatchStatement struct {
+	SQL  string
+	Args []any
+}
+
+// StatementSender is implemented by the transactions that send the statements of a unit of work themselves,
+// instead of as a pgx.Batch. Test doubles of Tx implement it, as pgx.Batch does not expose its statements.
+type StatementSender interface {
+	SendStatements(ctx context.Context, stmts []BatchStatement) pgx.BatchResults
+}
+
+// txUnitKey is used for storing the unit of work of the active transaction in the context.
+// It is namespaced by the connection of the Transactor, the zero key holding the unit of work
+// of the innermost transaction of any Transactor.
+type txUnitKey struct {
+	conn Conn
+}
+
+// Queue adds a statement to the unit of work of the transaction in the context, instead of executing it
+// right away. The queued statements are sent as a single pgx.Batch right before the transaction commits,
+// or the savepoint is released, saving a round trip per statement. They are discarded on rollback.
+// Call Flush before a read that depends on them.
+func Queue(ctx context.Context, sql string, args ...any) error {
+	return QueueWithResult(ctx, nil, sql, args...)
+}
+
+// QueueWithResult works like Queue and calls onResult with the result of the statement once it has been sent.
+// The error returned by onResult, which may be the one it received, fails the flush and rolls back the transaction.
+func QueueWithResult(ctx context.Context, onResult func(pgconn.CommandTag, error) error, sql string, args ...any) error {
+	unit, ok := ctx.Value(txUnitKey{}).(*txUnit)
+	if !ok {
+		return ErrNoTransaction
+	}
+
+	unit.Lock()
+	defer unit.Unlock()
+	unit.queue = append(unit.queue, queuedStatement{sql: sql, args: args, onResult: onResult})
+
+	return nil
+}
+
+// Flush sends the statements queued in the unit of work of the transaction in the context as a single pgx.Batch.
+// It returns the first error of a statement, after which the transaction is aborted.
+func Flush(ctx context.Context) error {
+	unit, ok := ctx.Value(txUnitKey{}).(*txUnit)
+	if !ok {
+		return ErrNoTransaction
+	}
+	return unit.flush(ctx)
+}
+
+// withTxUnit stores a new unit of work for tx in the context.
+func (t *Transactor) withTxUnit(ctx context.Context, tx Tx) context.Context {
+	unit := &txUnit{tx: tx}
+	ctx = context.WithValue(ctx, txUnitKey{conn: t.conn}, unit)
+	return context.WithValue(ctx, txUnitKey{}, unit)
+}
+
+// flushTxUnit flushes the unit of work of the Transactor in the context, if any.
+func (t *Transactor) flushTxUnit(ctx context.Context) error {
+	if unit, ok := ctx.Value(txUnitKey{conn: t.conn}).(*txUnit); ok {
+		return unit.flush(ctx)
+	}
+	return nil
+}
+
+// send sends the queued statements as a pgx.Batch, or through SendStatements when the transaction implements it.
+func (u *txUnit) send(ctx context.Context, queue []queuedStatement) pgx.BatchResults {
+	if sender, ok := u.tx.(StatementSender); ok {
+		stmts := make([]BatchStatement, len(queue))
+		for i, stmt := range queue {
+			stmts[i] = BatchStatement{SQL: stmt.sql, Args: stmt.args}
+		}
+		return sender.SendStatements(ctx, stmts)
+	}
+
+	batch := &pgx.Batch{}
+	for _, stmt := range queue {
+		batch.Queue(stmt.sql, stmt.args...)
+	}
+	return u.tx.SendBatch(ctx, batch)
+}
+
+// flush sends the queued statements and reports their results to their callbacks.
+func (u *txUnit) flush(ctx context.Context) (err error) {
+	u.Lock()
+	queue := u.queue
+	u.queue = nil
+	u.Unlock()
+
+	if len(queue) == 0 {
+		return nil
+	}
+
+	results := u.send(ctx, queue)
+	defer func() {
+		if closeErr := results.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	for _, stmt := range queue {
+		tag, execErr := results.Exec()
+		if stmt.onResult != nil {
+			execErr = stmt.onResult(tag, execErr)
+		}
+		if execErr != nil {
+			return execErr
+		}
+	}
+
+	return nil
+}
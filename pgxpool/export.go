@@ -0,0 +1,119 @@
+package pgxpool
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ExportFormat is the format of the data written by Export.
+type ExportFormat int
+
+const (
+	// ExportCSV writes CSV with a header line naming the columns.
+	ExportCSV ExportFormat = iota
+	// ExportNDJSON writes a JSON object per row, one per line. The keys are ordered as in jsonb:
+	// shorter ones first, then in byte order.
+	ExportNDJSON
+)
+
+const defaultProgressEvery = 1 << 20
+
+// ExportOptions configure an export.
+type ExportOptions struct {
+	Format ExportFormat
+	// Gzip compresses the output.
+	Gzip bool
+	// Progress is called with the number of bytes exported so far, before compression,
+	// every ProgressEvery bytes and once the export has completed.
+	Progress func(bytes int64)
+	// ProgressEvery is the number of bytes between calls to Progress (default: 1 MiB).
+	ProgressEvery int64
+}
+
+// Export writes the result of query to w with COPY (query) TO STDOUT on a slave node,
+// or on the master when there is none, and returns the number of rows exported.
+// See the Export function.
+func (p *Pools) Export(ctx context.Context, w io.Writer, query string, opts ExportOptions) (int64, error) {
+	return Export(ctx, p.Slave(), w, query, opts)
+}
+
+// Export writes the result of query to w with COPY (query) TO STDOUT through the pgconn of a connection
+// of the pool, and returns the number of rows exported. The data is streamed as the server produces it.
+// COPY does not accept parameters, so the values in query must be quoted into it.
+// A canceled context stops the export on the server.
+func Export(ctx context.Context, pool *Pool, w io.Writer, query string, opts ExportOptions) (n int64, err error) {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	out := w
+	if opts.Gzip {
+		zw := gzip.NewWriter(w)
+		defer func() {
+			if closeErr := zw.Close(); closeErr != nil && err == nil {
+				err = closeErr
+			}
+		}()
+		out = zw
+	}
+
+	pw := &progressWriter{w: out, progress: opts.Progress, every: opts.ProgressEvery}
+	if pw.every <= 0 {
+		pw.every = defaultProgressEvery
+	}
+
+	tag, err := conn.Conn().PgConn().CopyTo(ctx, pw, copyToSQL(query, opts.Format))
+	if err != nil {
+		return 0, err
+	}
+	pw.report()
+
+	return tag.RowsAffected(), nil
+}
+
+// copyToSQL returns the COPY statement exporting the result of query in the given format.
+func copyToSQL(query string, format ExportFormat) string {
+	query = strings.TrimRight(strings.TrimSpace(query), ";")
+
+	if format == ExportNDJSON {
+		// Through jsonb, each document is printed on a single line, even with json values spanning several.
+		// JSON escapes control characters, so with quote and delimiter characters that it never contains raw,
+		// the CSV format writes each document as is.
+		return fmt.Sprintf(
+			"COPY (SELECT row_to_json(q)::jsonb::text FROM (%s) q) TO STDOUT WITH (FORMAT csv, QUOTE E'\\x01', DELIMITER E'\\x02')",
+			query,
+		)
+	}
+
+	return fmt.Sprintf("COPY (%s) TO STDOUT WITH (FORMAT csv, HEADER)", query)
+}
+
+// progressWriter counts the bytes written through it and reports them to the progress callback.
+type progressWriter struct {
+	w        io.Writer
+	progress func(int64)
+	every    int64
+	written  int64
+	reported int64
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.written += int64(n)
+	if p.written-p.reported >= p.every {
+		p.report()
+	}
+	return n, err
+}
+
+func (p *progressWriter) report() {
+	p.reported = p.written
+	if p.progress != nil {
+		p.progress(p.written)
+	}
+}
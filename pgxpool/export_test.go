@@ -0,0 +1,70 @@
+package pgxpool
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/stretchr/testify/suite"
+)
+
+// ExportTestSuite defines the structure for the test suite.
+type ExportTestSuite struct {
+	suite.Suite
+}
+
+// TestCopyToSQL checks the COPY statements of the formats.
+func (t *ExportTestSuite) TestCopyToSQL() {
+	t.Equal("COPY (SELECT * FROM users) TO STDOUT WITH (FORMAT csv, HEADER)",
+		copyToSQL(" SELECT * FROM users; ", ExportCSV))
+	t.Equal(`COPY (SELECT row_to_json(q)::jsonb::text FROM (SELECT * FROM users) q) TO STDOUT WITH (FORMAT csv, QUOTE E'\x01', DELIMITER E'\x02')`,
+		copyToSQL("SELECT * FROM users", ExportNDJSON))
+}
+
+// TestExportNDJSONMultiline checks that a json value spanning several lines is exported on a single line.
+// It needs PGSQL_TEST_DSN.
+func (t *ExportTestSuite) TestExportNDJSONMultiline() {
+	dsn := os.Getenv("PGSQL_TEST_DSN")
+	if dsn == "" {
+		t.T().Skip("PGSQL_TEST_DSN is not set")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.Connect(ctx, dsn)
+	t.Require().NoError(err)
+	defer pool.Close()
+
+	var buf bytes.Buffer
+	n, err := Export(ctx, pool, &buf, `SELECT 1 AS id, E'{"note":\n "two\\nlines"}'::json AS doc`, ExportOptions{Format: ExportNDJSON})
+
+	t.NoError(err)
+	t.Equal(int64(1), n)
+	t.Equal(`{"id": 1, "doc": {"note": "two\nlines"}}`+"\n", buf.String())
+}
+
+// TestProgressWriter checks that the progress is reported every given number of bytes.
+func (t *ExportTestSuite) TestProgressWriter() {
+	var (
+		buf      bytes.Buffer
+		progress []int64
+	)
+	pw := &progressWriter{w: &buf, every: 10, progress: func(n int64) {
+		progress = append(progress, n)
+	}}
+
+	for i := 0; i < 5; i++ {
+		_, err := pw.Write([]byte("abcd"))
+		t.NoError(err)
+	}
+	pw.report()
+
+	t.Equal([]int64{12, 20}, progress)
+	t.Equal(20, buf.Len())
+}
+
+// TestExportSuite runs the test suite.
+func TestExportSuite(t *testing.T) {
+	suite.Run(t, new(ExportTestSuite))
+}
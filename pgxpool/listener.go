@@ -0,0 +1,281 @@
+package pgxpool
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	defaultReconnectDelay     = time.Second
+	defaultNotificationBuffer = 64
+)
+
+// Notification is a notification received on a channel.
+type Notification struct {
+	Channel string
+	Payload string
+	// PID is the process ID of the notifying backend.
+	PID uint32
+	// Gap reports that the connection was lost and re-established:
+	// the notifications sent in the meantime were missed.
+	Gap bool
+}
+
+// Listener receives the notifications of PostgreSQL channels on a dedicated connection of the master node
+// of a pool and fans them out to the subscribers of each channel. The connection is taken out of the pool
+// and closed when Run returns.
+type Listener struct {
+	pools          *Pools
+	reconnectDelay time.Duration
+	bufferSize     int
+	onError        func(context.Context, error)
+
+	mu         sync.Mutex
+	subs       map[string]map[*Subscription]struct{}
+	dirty      bool
+	cancelWait context.CancelFunc
+}
+
+// ListenerOption defines the type for functional options for Listener.
+type ListenerOption func(*Listener)
+
+// WithReconnectDelay is an option to set the delay before reconnecting after the connection is lost (default: 1s).
+func WithReconnectDelay(delay time.Duration) ListenerOption {
+	return func(l *Listener) {
+		l.reconnectDelay = delay
+	}
+}
+
+// WithNotificationBuffer is an option to set the size of the channel of each subscription (default: 64).
+func WithNotificationBuffer(size int) ListenerOption {
+	return func(l *Listener) {
+		l.bufferSize = size
+	}
+}
+
+// WithListenerErrorHandler is an option to handle the connection errors of Run.
+// By default, they are logged with log.Printf.
+func WithListenerErrorHandler(handler func(context.Context, error)) ListenerOption {
+	return func(l *Listener) {
+		l.onError = handler
+	}
+}
+
+// NewListener is Listener constructor. It listens on the pool with the given name of the registry.
+func NewListener(registry *Registry, name string, opts ...ListenerOption) (*Listener, error) {
+	pools, err := registry.GetPoolName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Listener{
+		pools:          pools,
+		reconnectDelay: defaultReconnectDelay,
+		bufferSize:     defaultNotificationBuffer,
+		subs:           make(map[string]map[*Subscription]struct{}),
+		onError: func(_ context.Context, err error) {
+			log.Printf("pgsql listener: %v", err)
+		},
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l, nil
+}
+
+// Subscription receives the notifications of a channel.
+type Subscription struct {
+	// C delivers the notifications. It is not closed by Unsubscribe.
+	C        <-chan Notification
+	c        chan Notification
+	channel  string
+	listener *Listener
+	done     chan struct{}
+	once     sync.Once
+}
+
+// Unsubscribe stops the delivery of the notifications. The channel is no longer listened to
+// once it has no subscriber.
+func (s *Subscription) Unsubscribe() {
+	s.once.Do(func() {
+		close(s.done)
+		s.listener.remove(s)
+	})
+}
+
+// Subscribe subscribes to the notifications of channel. The channel is listened to
+// from the next iteration of Run. A subscriber that does not drain its channel
+// holds up the delivery to the other subscribers.
+func (l *Listener) Subscribe(channel string) *Subscription {
+	c := make(chan Notification, l.bufferSize)
+	s := &Subscription{C: c, c: c, channel: channel, listener: l, done: make(chan struct{})}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.subs[channel] == nil {
+		l.subs[channel] = make(map[*Subscription]struct{})
+		l.changed()
+	}
+	l.subs[channel][s] = struct{}{}
+
+	return s
+}
+
+func (l *Listener) remove(s *Subscription) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.subs[s.channel], s)
+	if len(l.subs[s.channel]) == 0 {
+		delete(l.subs, s.channel)
+		l.changed()
+	}
+}
+
+// changed interrupts the wait for notifications, so that the channels are listened to again.
+// It must be called with the lock held.
+func (l *Listener) changed() {
+	l.dirty = true
+	if l.cancelWait != nil {
+		l.cancelWait()
+	}
+}
+
+// Run listens until the context is done. When the connection is lost, it reconnects after
+// the reconnect delay, listens to the channels again and sends a gap notification to every subscriber.
+func (l *Listener) Run(ctx context.Context) {
+	var connected bool
+	for {
+		established, err := l.listen(ctx, connected)
+		if ctx.Err() != nil {
+			return
+		}
+		connected = connected || established
+		l.onError(ctx, err)
+
+		timer := time.NewTimer(l.reconnectDelay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// listen acquires a connection and dispatches its notifications until it fails.
+// It reports whether the channels were listened to on the connection.
+func (l *Listener) listen(ctx context.Context, gap bool) (bool, error) {
+	pc, err := l.pools.Master().Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	conn := pc.Hijack()
+	defer conn.Close(context.Background())
+
+	listened := make(map[string]bool)
+	if err = l.sync(ctx, conn, listened); err != nil {
+		return false, err
+	}
+	if gap {
+		for channel := range listened {
+			l.dispatch(ctx, Notification{Channel: channel, Gap: true})
+		}
+	}
+
+	for {
+		waitCtx, cancel := context.WithCancel(ctx)
+
+		l.mu.Lock()
+		dirty := l.dirty
+		if !dirty {
+			l.cancelWait = cancel
+		}
+		l.mu.Unlock()
+
+		var n *pgconn.Notification
+		if !dirty {
+			n, err = conn.WaitForNotification(waitCtx)
+		}
+
+		l.mu.Lock()
+		l.cancelWait = nil
+		l.mu.Unlock()
+		interrupted := waitCtx.Err() != nil
+		cancel()
+
+		switch {
+		case ctx.Err() != nil:
+			return true, ctx.Err()
+		case dirty || interrupted && errors.Is(err, context.Canceled):
+			if err = l.sync(ctx, conn, listened); err != nil {
+				return true, err
+			}
+		case err != nil:
+			return true, err
+		default:
+			l.dispatch(ctx, Notification{Channel: n.Channel, Payload: n.Payload, PID: n.PID})
+		}
+	}
+}
+
+// sync listens to the subscribed channels that are not listened to yet, and stops listening
+// to the channels without subscribers.
+func (l *Listener) sync(ctx context.Context, conn *pgx.Conn, listened map[string]bool) error {
+	l.mu.Lock()
+	l.dirty = false
+	var listen, unlisten []string
+	for channel := range l.subs {
+		if !listened[channel] {
+			listen = append(listen, channel)
+		}
+	}
+	for channel := range listened {
+		if _, ok := l.subs[channel]; !ok {
+			unlisten = append(unlisten, channel)
+		}
+	}
+	l.mu.Unlock()
+
+	for _, channel := range listen {
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return err
+		}
+		listened[channel] = true
+	}
+	for _, channel := range unlisten {
+		if _, err := conn.Exec(ctx, "UNLISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return err
+		}
+		delete(listened, channel)
+	}
+
+	return nil
+}
+
+// dispatch delivers the notification to the subscribers of its channel.
+func (l *Listener) dispatch(ctx context.Context, n Notification) {
+	l.mu.Lock()
+	subs := make([]*Subscription, 0, len(l.subs[n.Channel]))
+	for s := range l.subs[n.Channel] {
+		subs = append(subs, s)
+	}
+	l.mu.Unlock()
+
+	for _, s := range subs {
+		select {
+		case s.c <- n:
+		case <-s.done:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package pgxpool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// ListenerTestSuite defines the structure for the test suite.
+type ListenerTestSuite struct {
+	suite.Suite
+}
+
+func (t *ListenerTestSuite) newListener() *Listener {
+	registry, err := NewWithConfigOptions(
+		WithConfig(DEFAULT, Config{
+			Nodes:       []string{"postgres://localhost:5432/master"},
+			LazyConnect: true,
+		}),
+	)
+	t.Require().NoError(err)
+	t.T().Cleanup(func() { _ = registry.Close() })
+
+	l, err := NewListener(registry, DEFAULT, WithNotificationBuffer(1))
+	t.Require().NoError(err)
+
+	return l
+}
+
+// TestNewListenerUnknownPool checks that the pool must be registered.
+func (t *ListenerTestSuite) TestNewListenerUnknownPool() {
+	registry, err := NewRegistry(Configs{})
+	t.Require().NoError(err)
+
+	_, err = NewListener(registry, "orders")
+	t.ErrorIs(err, ErrUnknownPool)
+}
+
+// TestDispatch checks that a notification is fanned out to the subscribers of its channel only.
+func (t *ListenerTestSuite) TestDispatch() {
+	l := t.newListener()
+	orders1, orders2, billing := l.Subscribe("orders"), l.Subscribe("orders"), l.Subscribe("billing")
+
+	l.dispatch(context.Background(), Notification{Channel: "orders", Payload: "42"})
+
+	t.Equal(Notification{Channel: "orders", Payload: "42"}, <-orders1.C)
+	t.Equal(Notification{Channel: "orders", Payload: "42"}, <-orders2.C)
+	t.Empty(billing.C)
+}
+
+// TestUnsubscribe checks that the channels without subscribers are marked to be unlistened,
+// and that an unsubscribed subscriber does not hold up the delivery.
+func (t *ListenerTestSuite) TestUnsubscribe() {
+	l := t.newListener()
+	interrupted := false
+	l.cancelWait = func() { interrupted = true }
+
+	s1, s2 := l.Subscribe("orders"), l.Subscribe("orders")
+	t.True(interrupted)
+	t.Len(l.subs["orders"], 2)
+
+	l.dirty, interrupted = false, false
+	s1.Unsubscribe()
+	t.False(l.dirty)
+	t.False(interrupted)
+
+	done := make(chan struct{})
+	go func() {
+		// The buffer of s2 is full after the first notification.
+		l.dispatch(context.Background(), Notification{Channel: "orders"})
+		l.dispatch(context.Background(), Notification{Channel: "orders"})
+		close(done)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	s2.Unsubscribe()
+	<-done
+
+	t.True(l.dirty)
+	t.True(interrupted)
+	t.Empty(l.subs)
+}
+
+// TestListenerSuite runs the test suite.
+func TestListenerSuite(t *testing.T) {
+	suite.Run(t, new(ListenerTestSuite))
+}
@@ -0,0 +1,115 @@
+package pgxpool
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MetricsSink receives the metrics of the pools and of the transactions managed by the pgx.TxManager.
+// Labels identify the series a value belongs to, such as the pool name and the node index.
+type MetricsSink interface {
+	SetGauge(name string, labels map[string]string, value float64)
+	AddCounter(name string, labels map[string]string, delta float64)
+	ObserveHistogram(name string, labels map[string]string, value float64)
+}
+
+// StatsCollector samples the statistics of every node of every pool in the Registry.
+type StatsCollector struct {
+	registry *Registry
+	sink     MetricsSink
+
+	mu   sync.Mutex
+	last map[string]counters
+}
+
+// counters are the cumulative statistics of a node, reported to the sink as deltas.
+type counters struct {
+	acquire         int64
+	acquireDuration time.Duration
+	emptyAcquire    int64
+	canceledAcquire int64
+	newConns        int64
+	lifetimeDestroy int64
+	idleDestroy     int64
+}
+
+// NewStatsCollector is StatsCollector constructor.
+func NewStatsCollector(registry *Registry, sink MetricsSink) *StatsCollector {
+	return &StatsCollector{
+		registry: registry,
+		sink:     sink,
+		last:     make(map[string]counters),
+	}
+}
+
+// Run samples the statistics every interval until the context is done.
+func (c *StatsCollector) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		c.Collect()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Collect samples the statistics once.
+func (c *StatsCollector) Collect() {
+	c.registry.Lock()
+	pools := make(map[string]*Pools, len(c.registry.pools))
+	for name, p := range c.registry.pools {
+		pools[name] = p
+	}
+	c.registry.Unlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for name, p := range pools {
+		for i, pool := range p.pools {
+			c.collect(name, i, pool)
+		}
+	}
+}
+
+// collect reports the statistics of a single node.
+func (c *StatsCollector) collect(name string, node int, pool *Pool) {
+	var (
+		stat   = pool.Stat()
+		labels = map[string]string{"pool": name, "node": strconv.Itoa(node)}
+		key    = name + "/" + labels["node"]
+		last   = c.last[key]
+		cur    = counters{
+			acquire:         stat.AcquireCount(),
+			acquireDuration: stat.AcquireDuration(),
+			emptyAcquire:    stat.EmptyAcquireCount(),
+			canceledAcquire: stat.CanceledAcquireCount(),
+			newConns:        stat.NewConnsCount(),
+			lifetimeDestroy: stat.MaxLifetimeDestroyCount(),
+			idleDestroy:     stat.MaxIdleDestroyCount(),
+		}
+	)
+
+	c.sink.SetGauge("pgsql_pool_total_conns", labels, float64(stat.TotalConns()))
+	c.sink.SetGauge("pgsql_pool_acquired_conns", labels, float64(stat.AcquiredConns()))
+	c.sink.SetGauge("pgsql_pool_idle_conns", labels, float64(stat.IdleConns()))
+	c.sink.SetGauge("pgsql_pool_constructing_conns", labels, float64(stat.ConstructingConns()))
+	c.sink.SetGauge("pgsql_pool_max_conns", labels, float64(stat.MaxConns()))
+
+	c.sink.AddCounter("pgsql_pool_acquire_total", labels, float64(cur.acquire-last.acquire))
+	c.sink.AddCounter("pgsql_pool_acquire_duration_seconds_total", labels, (cur.acquireDuration - last.acquireDuration).Seconds())
+	c.sink.AddCounter("pgsql_pool_empty_acquire_total", labels, float64(cur.emptyAcquire-last.emptyAcquire))
+	c.sink.AddCounter("pgsql_pool_canceled_acquire_total", labels, float64(cur.canceledAcquire-last.canceledAcquire))
+	c.sink.AddCounter("pgsql_pool_new_conns_total", labels, float64(cur.newConns-last.newConns))
+	c.sink.AddCounter("pgsql_pool_max_lifetime_destroy_total", labels, float64(cur.lifetimeDestroy-last.lifetimeDestroy))
+	c.sink.AddCounter("pgsql_pool_max_idle_destroy_total", labels, float64(cur.idleDestroy-last.idleDestroy))
+
+	c.last[key] = cur
+}
@@ -0,0 +1,66 @@
+package pgxpool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// recordingSink records the gauges and counters it receives, keyed by name and node.
+type recordingSink struct {
+	gauges   map[string]float64
+	counters map[string]float64
+}
+
+func (r *recordingSink) SetGauge(name string, labels map[string]string, value float64) {
+	r.gauges[labels["pool"]+"/"+labels["node"]+"/"+name] = value
+}
+
+func (r *recordingSink) AddCounter(name string, labels map[string]string, delta float64) {
+	r.counters[labels["pool"]+"/"+labels["node"]+"/"+name] += delta
+}
+
+func (r *recordingSink) ObserveHistogram(string, map[string]string, float64) {}
+
+// StatsCollectorTestSuite defines the structure for the test suite.
+type StatsCollectorTestSuite struct {
+	suite.Suite
+	registry *Registry
+}
+
+// SetupTest creates a registry of lazily connected pools, so that no database is needed.
+func (t *StatsCollectorTestSuite) SetupTest() {
+	registry, err := NewWithConfigOptions(
+		WithConfig(DEFAULT, Config{
+			Nodes:       []string{"postgres://localhost:5432/master", "postgres://localhost:5432/slave"},
+			MaxConns:    8,
+			LazyConnect: true,
+		}),
+	)
+	t.Require().NoError(err)
+	t.registry = registry
+}
+
+// TearDownTest closes the pools.
+func (t *StatsCollectorTestSuite) TearDownTest() {
+	_ = t.registry.Close()
+}
+
+// TestCollect checks that every node of every pool is sampled.
+func (t *StatsCollectorTestSuite) TestCollect() {
+	sink := &recordingSink{gauges: make(map[string]float64), counters: make(map[string]float64)}
+	collector := NewStatsCollector(t.registry, sink)
+
+	collector.Collect()
+	collector.Collect()
+
+	t.Equal(float64(8), sink.gauges["default/0/pgsql_pool_max_conns"])
+	t.Equal(float64(8), sink.gauges["default/1/pgsql_pool_max_conns"])
+	t.Equal(float64(0), sink.gauges["default/1/pgsql_pool_total_conns"])
+	t.Equal(float64(0), sink.counters["default/0/pgsql_pool_acquire_total"])
+}
+
+// TestStatsCollectorSuite runs the test suite.
+func TestStatsCollectorSuite(t *testing.T) {
+	suite.Run(t, new(StatsCollectorTestSuite))
+}
@@ -13,7 +13,9 @@ type Pool = pgxpool.Pool
 
 const cfgParamName = "pgsql.pgpool"
 
-func NewWithViper(cfg *viper.Viper) (*Registry, error) {
+// NewWithViper creates a new Registry from the "pgsql.pgpool" section of the viper configuration.
+// The options are applied to the configurations read from it.
+func NewWithViper(cfg *viper.Viper, opts ...ConfigOption) (*Registry, error) {
 	var (
 		keys   = cfg.Sub(cfgParamName).AllKeys()
 		config = make(Configs, len(keys))
@@ -39,6 +41,10 @@ func NewWithViper(cfg *viper.Viper) (*Registry, error) {
 		config[name] = conf
 	}
 
+	for _, opt := range opts {
+		opt(config)
+	}
+
 	return NewRegistry(config)
 }
 
@@ -79,3 +85,14 @@ func WithConfig(name string, cfg Config) ConfigOption {
 		configs[name] = cfg
 	}
 }
+
+// WithPreparedStatements is an option to prepare the statements, by name, on every new connection
+// of the pool with the given name. A statement is then executed by passing its name as the SQL.
+// The pool may be configured before or after the option.
+func WithPreparedStatements(name string, statements map[string]string) ConfigOption {
+	return func(configs map[string]Config) {
+		cfg := configs[name]
+		cfg.PreparedStatements = statements
+		configs[name] = cfg
+	}
+}
@@ -2,8 +2,12 @@ package pgxpool
 
 import (
 	"context"
+	"fmt"
+	"strconv"
 	"sync/atomic"
+	"time"
 
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
@@ -15,9 +19,14 @@ type Pools struct {
 
 // Open creates new pools for each node
 func Open(config Config) (*Pools, error) {
+	return open("", config)
+}
+
+// open creates new pools for each node of the pool with the given name
+func open(name string, config Config) (*Pools, error) {
 	pools := make([]*Pool, 0, len(config.Nodes))
 
-	for _, node := range config.Nodes {
+	for i, node := range config.Nodes {
 		c, err := pgxpool.ParseConfig(node)
 		if err != nil {
 			return nil, err
@@ -34,6 +43,17 @@ func Open(config Config) (*Pools, error) {
 		if config.PreferSimpleProtocol {
 			c.ConnConfig.RuntimeParams["standard_conforming_strings"] = "on"
 		}
+		setTimeoutParams(c.ConnConfig.RuntimeParams, config)
+
+		if config.Logger != nil {
+			c.ConnConfig.Logger = config.Logger
+		}
+		if config.Tracer != nil {
+			c.ConnConfig.Logger = traceLogger{tracer: config.Tracer, pool: name, node: i, next: c.ConnConfig.Logger}
+		}
+		if len(config.PreparedStatements) > 0 {
+			c.AfterConnect = prepareStatements(config.PreparedStatements)
+		}
 
 		pool, err := pgxpool.ConnectConfig(context.Background(), c)
 		if err != nil {
@@ -46,6 +66,37 @@ func Open(config Config) (*Pools, error) {
 	return &Pools{pools: pools}, nil
 }
 
+// setTimeoutParams sets the session timeouts of the config as runtime parameters of new connections
+func setTimeoutParams(params map[string]string, config Config) {
+	for name, value := range map[string]time.Duration{
+		"statement_timeout":                   config.StatementTimeout,
+		"lock_timeout":                        config.LockTimeout,
+		"idle_in_transaction_session_timeout": config.IdleInTransactionSessionTimeout,
+	} {
+		if value > 0 {
+			params[name] = strconv.FormatInt(milliseconds(value), 10)
+		}
+	}
+}
+
+// milliseconds returns d in milliseconds, rounded up: a sub-millisecond timeout
+// must not turn into 0, which disables it.
+func milliseconds(d time.Duration) int64 {
+	return int64((d + time.Millisecond - 1) / time.Millisecond)
+}
+
+// prepareStatements returns the hook preparing the statements on a new connection.
+func prepareStatements(statements map[string]string) func(context.Context, *pgx.Conn) error {
+	return func(ctx context.Context, conn *pgx.Conn) error {
+		for name, sql := range statements {
+			if _, err := conn.Prepare(ctx, name, sql); err != nil {
+				return fmt.Errorf("failed to prepare statement %s: %w", name, err)
+			}
+		}
+		return nil
+	}
+}
+
 // Close closes all connections in the pool and rejects future Acquire calls
 func (p *Pools) Close() {
 	for _, pool := range p.pools {
@@ -60,7 +111,19 @@ func (p *Pools) Master() *Pool {
 
 // Slave returns slave connections pool
 func (p *Pools) Slave() *Pool {
-	return p.pools[p.slave(len(p.pools))]
+	pool, _ := p.SlaveNode()
+	return pool
+}
+
+// SlaveNode returns slave connections pool together with the index of its node
+func (p *Pools) SlaveNode() (*Pool, int) {
+	i := p.slave(len(p.pools))
+	return p.pools[i], i
+}
+
+// HasSlaves reports whether the pools contain at least one slave node
+func (p *Pools) HasSlaves() bool {
+	return len(p.pools) > 1
 }
 
 func (p *Pools) slave(n int) int {
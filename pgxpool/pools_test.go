@@ -44,6 +44,12 @@ func (t *LoadBalancingTestSuite) TestLoadBalancing() {
 	}
 }
 
+// TestHasSlaves checks that a single node is not reported as a slave.
+func (t *LoadBalancingTestSuite) TestHasSlaves() {
+	t.True(t.pools.HasSlaves())
+	t.False((&Pools{pools: make([]*Pool, 1)}).HasSlaves())
+}
+
 // TestLoadBalancingSuite runs the test suite.
 func TestLoadBalancingSuite(t *testing.T) {
 	suite.Run(t, new(LoadBalancingTestSuite))
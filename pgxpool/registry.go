@@ -2,8 +2,11 @@ package pgxpool
 
 import (
 	"errors"
+	"sort"
 	"sync"
 	"time"
+
+	"github.com/jackc/pgx/v4"
 )
 
 const (
@@ -30,6 +33,16 @@ type (
 		HealthCheckPeriod    time.Duration `mapstructure:"health_check_period" json:"health_check_period"`
 		LazyConnect          bool          `mapstructure:"lazy_conn" json:"lazy_conn"`
 		PreferSimpleProtocol bool          `mapstructure:"prefer_simple_protocol" json:"prefer_simple_protocol"`
+		// Session timeouts enforced on every connection of the pool. Zero leaves the server default.
+		StatementTimeout                time.Duration `mapstructure:"statement_timeout" json:"statement_timeout"`
+		LockTimeout                     time.Duration `mapstructure:"lock_timeout" json:"lock_timeout"`
+		IdleInTransactionSessionTimeout time.Duration `mapstructure:"idle_in_transaction_session_timeout" json:"idle_in_transaction_session_timeout"`
+		// Logger receives the log events of pgx, such as the queries executed on the pool.
+		Logger pgx.Logger `mapstructure:"-" json:"-"`
+		// Tracer receives the queries executed on the pool. The events are still passed to the Logger.
+		Tracer Tracer `mapstructure:"-" json:"-"`
+		// PreparedStatements are prepared on every new connection of the pool, by name.
+		PreparedStatements map[string]string `mapstructure:"-" json:"-"`
 	}
 
 	// Registry is database pool registry.
@@ -50,7 +63,7 @@ func NewRegistry(configs Configs) (*Registry, error) {
 	pools := make(map[string]*Pools, len(configs))
 
 	for name, config := range configs {
-		p, err := Open(config)
+		p, err := open(name, config)
 		if err != nil {
 			return nil, err
 		}
@@ -123,6 +136,20 @@ func (r *Registry) GetPoolName(name string) (*Pools, error) {
 	return nil, ErrUnknownPool
 }
 
+// Names returns the names of the pools in the registry, sorted.
+func (r *Registry) Names() []string {
+	r.Lock()
+	defer r.Unlock()
+
+	names := make([]string, 0, len(r.pools))
+	for name := range r.pools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
 // mergeConfigs merges two Config objects. Non-zero values in cfg2 override values in cfg1.
 func merge(old Config, new Config) Config {
 	if new.MaxConns != 0 {
@@ -143,6 +170,24 @@ func merge(old Config, new Config) Config {
 	if len(new.Nodes) > 0 {
 		old.Nodes = new.Nodes
 	}
+	if new.StatementTimeout != 0 {
+		old.StatementTimeout = new.StatementTimeout
+	}
+	if new.LockTimeout != 0 {
+		old.LockTimeout = new.LockTimeout
+	}
+	if new.IdleInTransactionSessionTimeout != 0 {
+		old.IdleInTransactionSessionTimeout = new.IdleInTransactionSessionTimeout
+	}
+	if new.Logger != nil {
+		old.Logger = new.Logger
+	}
+	if new.Tracer != nil {
+		old.Tracer = new.Tracer
+	}
+	if new.PreparedStatements != nil {
+		old.PreparedStatements = new.PreparedStatements
+	}
 	old.LazyConnect = new.LazyConnect
 	old.PreferSimpleProtocol = new.PreferSimpleProtocol
 
@@ -2,6 +2,7 @@ package pgxpool
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
 )
@@ -37,6 +38,48 @@ func (t *RegistryTestSuite) TestGetPoolNameNotFound() {
 	t.Equal(ErrUnknownPool, err, "Error should be ErrUnknownPool for a non-existent pool name")
 }
 
+// TestSetTimeoutParams checks that configured session timeouts become runtime parameters in milliseconds.
+func (t *RegistryTestSuite) TestSetTimeoutParams() {
+	t.T().Parallel()
+
+	params := map[string]string{}
+	setTimeoutParams(params, Config{StatementTimeout: 5 * time.Second, LockTimeout: 500 * time.Millisecond})
+
+	t.Equal(map[string]string{"statement_timeout": "5000", "lock_timeout": "500"}, params,
+		"Only the configured timeouts should be set")
+
+	params = map[string]string{}
+	setTimeoutParams(params, Config{StatementTimeout: 1500 * time.Microsecond, LockTimeout: time.Microsecond})
+
+	t.Equal(map[string]string{"statement_timeout": "2", "lock_timeout": "1"}, params,
+		"Sub-millisecond timeouts should be rounded up rather than disabled")
+}
+
+// TestWithPreparedStatements checks that the statements apply to the pool with the given name only,
+// whether it is configured before or after the option, and are kept when it is configured again.
+func (t *RegistryTestSuite) TestWithPreparedStatements() {
+	statements := map[string]string{"GetUser": "SELECT id, name FROM users WHERE id = $1"}
+	reports := map[string]string{"GetTotals": "SELECT sum(total) FROM orders"}
+	configs := make(map[string]Config)
+
+	for _, opt := range []ConfigOption{
+		WithConfig(DEFAULT, Config{Nodes: []string{"postgres://localhost:5432/master"}}),
+		WithPreparedStatements(DEFAULT, statements),
+		WithConfig(DEFAULT, Config{MaxConns: 8}),
+		WithPreparedStatements("reports", reports),
+		WithConfig("reports", Config{Nodes: []string{"postgres://localhost:5432/reports"}}),
+		WithConfig("audit", Config{Nodes: []string{"postgres://localhost:5432/audit"}}),
+	} {
+		opt(configs)
+	}
+
+	t.Equal(statements, configs[DEFAULT].PreparedStatements)
+	t.Equal(int32(8), configs[DEFAULT].MaxConns)
+	t.Equal(reports, configs["reports"].PreparedStatements)
+	t.Equal([]string{"postgres://localhost:5432/reports"}, configs["reports"].Nodes)
+	t.Nil(configs["audit"].PreparedStatements)
+}
+
 // RegistrySuite runs the test suite.
 func TestRegistrySuite(t *testing.T) {
 	t.Parallel()
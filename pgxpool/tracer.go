@@ -0,0 +1,123 @@
+package pgxpool
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Tracer receives the lifecycle events of queries executed on the pools and of
+// transactions managed by the pgx.TxManager, so that they can be passed to
+// OpenTelemetry or any other tracing system.
+//
+// The Trace*Start methods return the context used for the matching Trace*End call
+// and, for transactions, for the work done inside them.
+//
+// Queries are traced from the log events of pgx v4, which are emitted once a query has completed:
+// TraceQueryStart and TraceQueryEnd are both called at that moment, with the actual start time
+// of the query in TraceQueryStartData. A span started in TraceQueryStart therefore cannot be
+// the parent of work done while the query runs.
+type Tracer interface {
+	TraceQueryStart(ctx context.Context, data TraceQueryStartData) context.Context
+	TraceQueryEnd(ctx context.Context, data TraceQueryEndData)
+	TraceTxStart(ctx context.Context, data TraceTxStartData) context.Context
+	TraceTxEnd(ctx context.Context, data TraceTxEndData)
+}
+
+// TraceQueryStartData describes a query being executed.
+type TraceQueryStartData struct {
+	Pool      string
+	Node      int
+	SQL       string
+	ArgsCount int
+	StartTime time.Time
+}
+
+// TraceQueryEndData describes the outcome of a query.
+type TraceQueryEndData struct {
+	Pool     string
+	Node     int
+	SQL      string
+	Duration time.Duration
+	Err      error
+}
+
+// TraceTxStartData describes a transaction being started. Depth is 1 for the
+// outermost transaction and grows by one for each nested savepoint.
+type TraceTxStartData struct {
+	Pool      string
+	Node      int
+	Depth     int
+	Options   pgx.TxOptions
+	StartTime time.Time
+}
+
+// TraceTxEndData describes the outcome of a transaction.
+type TraceTxEndData struct {
+	Pool      string
+	Node      int
+	Depth     int
+	Committed bool
+	Duration  time.Duration
+	Err       error
+}
+
+// WithTracer is an option to trace the queries of all the pools configured so far.
+// It must follow the WithConfig options of the pools it applies to. The Logger of the pools,
+// if any, keeps receiving the log events of pgx.
+func WithTracer(tracer Tracer) ConfigOption {
+	return func(configs map[string]Config) {
+		for name, cfg := range configs {
+			cfg.Tracer = tracer
+			configs[name] = cfg
+		}
+	}
+}
+
+// traceLogger passes the queries logged by pgx to a Tracer, and every log event to the next logger, if any.
+// pgx v4 logs a query once it has completed, so both events are reported at
+// that moment, with the start time of the query in TraceQueryStartData.
+type traceLogger struct {
+	tracer Tracer
+	pool   string
+	node   int
+	next   pgx.Logger
+}
+
+// Log implements pgx.Logger.
+func (l traceLogger) Log(ctx context.Context, level pgx.LogLevel, msg string, data map[string]interface{}) {
+	if l.next != nil {
+		l.next.Log(ctx, level, msg, data)
+	}
+
+	sql, ok := data["sql"].(string)
+	if !ok {
+		// Only COPY is logged without the statement text.
+		table, isCopy := data["tableName"].(pgx.Identifier)
+		if !isCopy {
+			return
+		}
+		sql = fmt.Sprintf("COPY %s FROM STDIN", table.Sanitize())
+	}
+
+	duration, _ := data["time"].(time.Duration)
+	args, _ := data["args"].([]interface{})
+	err, _ := data["err"].(error)
+
+	ctx = l.tracer.TraceQueryStart(ctx, TraceQueryStartData{
+		Pool:      l.pool,
+		Node:      l.node,
+		SQL:       sql,
+		ArgsCount: len(args),
+		StartTime: time.Now().Add(-duration),
+	})
+	l.tracer.TraceQueryEnd(ctx, TraceQueryEndData{
+		Pool:     l.pool,
+		Node:     l.node,
+		SQL:      sql,
+		Duration: duration,
+		Err:      err,
+	})
+}
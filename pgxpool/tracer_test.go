@@ -0,0 +1,103 @@
+package pgxpool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/suite"
+)
+
+// recordingTracer records the query events it receives.
+type recordingTracer struct {
+	starts []TraceQueryStartData
+	ends   []TraceQueryEndData
+}
+
+func (r *recordingTracer) TraceQueryStart(ctx context.Context, data TraceQueryStartData) context.Context {
+	r.starts = append(r.starts, data)
+	return ctx
+}
+
+func (r *recordingTracer) TraceQueryEnd(_ context.Context, data TraceQueryEndData) {
+	r.ends = append(r.ends, data)
+}
+
+func (r *recordingTracer) TraceTxStart(ctx context.Context, _ TraceTxStartData) context.Context {
+	return ctx
+}
+
+func (r *recordingTracer) TraceTxEnd(context.Context, TraceTxEndData) {}
+
+type TracerTestSuite struct {
+	suite.Suite
+}
+
+// TestTraceLoggerQuery checks that a logged query is reported with its pool, node, arguments and error.
+func (t *TracerTestSuite) TestTraceLoggerQuery() {
+	tracer := new(recordingTracer)
+	logger := traceLogger{tracer: tracer, pool: "billing", node: 1}
+	queryErr := errors.New("relation does not exist")
+
+	logger.Log(context.Background(), pgx.LogLevelError, "Exec", map[string]interface{}{
+		"sql":  "DELETE FROM invoices WHERE id = $1",
+		"args": []interface{}{1},
+		"err":  queryErr,
+		"time": time.Second,
+	})
+
+	t.Len(tracer.starts, 1)
+	t.Equal("billing", tracer.starts[0].Pool)
+	t.Equal(1, tracer.starts[0].Node)
+	t.Equal(1, tracer.starts[0].ArgsCount)
+	t.WithinDuration(time.Now().Add(-time.Second), tracer.starts[0].StartTime, 100*time.Millisecond)
+
+	t.Len(tracer.ends, 1)
+	t.Equal("DELETE FROM invoices WHERE id = $1", tracer.ends[0].SQL)
+	t.Equal(time.Second, tracer.ends[0].Duration)
+	t.Equal(queryErr, tracer.ends[0].Err)
+}
+
+// TestTraceLoggerIgnoresOtherEvents checks that connection events are not reported as queries.
+func (t *TracerTestSuite) TestTraceLoggerIgnoresOtherEvents() {
+	tracer := new(recordingTracer)
+	logger := traceLogger{tracer: tracer}
+
+	logger.Log(context.Background(), pgx.LogLevelInfo, "closed connection", nil)
+	logger.Log(context.Background(), pgx.LogLevelInfo, "CopyFrom", map[string]interface{}{
+		"tableName": pgx.Identifier{"events"},
+		"time":      time.Millisecond,
+	})
+
+	t.Len(tracer.ends, 1)
+	t.Equal(`COPY "events" FROM STDIN`, tracer.ends[0].SQL)
+}
+
+// recordingLogger records the messages it receives.
+type recordingLogger struct {
+	msgs []string
+}
+
+func (r *recordingLogger) Log(_ context.Context, _ pgx.LogLevel, msg string, _ map[string]interface{}) {
+	r.msgs = append(r.msgs, msg)
+}
+
+// TestTraceLoggerChainsLogger checks that every event is still passed to the logger of the pool.
+func (t *TracerTestSuite) TestTraceLoggerChainsLogger() {
+	tracer := new(recordingTracer)
+	next := new(recordingLogger)
+	logger := traceLogger{tracer: tracer, next: next}
+
+	logger.Log(context.Background(), pgx.LogLevelInfo, "closed connection", nil)
+	logger.Log(context.Background(), pgx.LogLevelInfo, "Query", map[string]interface{}{"sql": "SELECT 1"})
+
+	t.Equal([]string{"closed connection", "Query"}, next.msgs)
+	t.Len(tracer.ends, 1)
+}
+
+// TestTracerSuite runs the test suite.
+func TestTracerSuite(t *testing.T) {
+	suite.Run(t, new(TracerTestSuite))
+}
@@ -0,0 +1,133 @@
+package pgxtest
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/i4erkasov/go-pgsql/pgx"
+	"github.com/jackc/pgconn"
+	jackc "github.com/jackc/pgx/v4"
+)
+
+// Expectation is a call expected by a Fake, with the result it returns.
+type Expectation struct {
+	kind      string
+	sql       string
+	name      string
+	columns   []string
+	args      []any
+	checkArgs bool
+	txOptions *pgx.TxOptions
+	tag       pgconn.CommandTag
+	rows      *Rows
+	err       error
+	met       bool
+}
+
+// WithArgs sets the expected arguments. They are compared with reflect.DeepEqual, unless they are an Argument.
+// By default, any arguments are accepted.
+func (e *Expectation) WithArgs(args ...any) *Expectation {
+	e.args = args
+	e.checkArgs = true
+	return e
+}
+
+// WithTxOptions sets the expected options of a transaction begun with BeginTx.
+func (e *Expectation) WithTxOptions(txOptions pgx.TxOptions) *Expectation {
+	e.txOptions = &txOptions
+	return e
+}
+
+// WillReturnResult sets the command tag returned by Exec, or the number of rows returned by CopyFrom.
+func (e *Expectation) WillReturnResult(tag pgconn.CommandTag) *Expectation {
+	e.tag = tag
+	return e
+}
+
+// WillReturnRows sets the rows returned by a query. By default, it returns no rows.
+func (e *Expectation) WillReturnRows(rows *Rows) *Expectation {
+	e.rows = rows
+	return e
+}
+
+// WillReturnError sets the error returned by the call.
+func (e *Expectation) WillReturnError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+// String describes the expectation.
+func (e *Expectation) String() string {
+	switch e.kind {
+	case kindExec, kindQuery, kindPrepare:
+		if e.checkArgs {
+			return fmt.Sprintf("%s %q with args %v", e.kind, e.sql, e.args)
+		}
+		return fmt.Sprintf("%s %q", e.kind, e.sql)
+	case kindCopyFrom:
+		return fmt.Sprintf("%s %s %v", e.kind, e.name, e.columns)
+	default:
+		return e.kind
+	}
+}
+
+// match reports why the call does not match the expectation.
+func (e *Expectation) match(c call, matcher QueryMatcher) error {
+	if e.kind != c.kind && !(c.kind == kindStatement && (e.kind == kindExec || e.kind == kindQuery)) {
+		return fmt.Errorf("got %s", c.kind)
+	}
+
+	switch e.kind {
+	case kindBegin:
+		if e.txOptions != nil && *e.txOptions != c.txOptions {
+			return fmt.Errorf("transaction options %+v are not %+v", c.txOptions, *e.txOptions)
+		}
+	case kindPrepare:
+		if e.name != c.name {
+			return fmt.Errorf("statement name %q is not %q", c.name, e.name)
+		}
+		return matcher(e.sql, c.sql)
+	case kindCopyFrom:
+		if e.name != c.name || !reflect.DeepEqual(e.columns, c.columns) {
+			return fmt.Errorf("%s %v is not %s %v", c.name, c.columns, e.name, e.columns)
+		}
+	case kindExec, kindQuery:
+		if err := matcher(e.sql, c.sql); err != nil {
+			return err
+		}
+		if e.checkArgs && !matchArgs(e.args, c.args) {
+			return fmt.Errorf("args %v do not match %v", c.args, e.args)
+		}
+	}
+
+	return nil
+}
+
+// result returns the rows of a query.
+func (e *Expectation) result() (jackc.Rows, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	if e.rows == nil {
+		return NewRows(), nil
+	}
+	return e.rows, nil
+}
+
+func matchArgs(expected, actual []any) bool {
+	if len(expected) != len(actual) {
+		return false
+	}
+	for i, arg := range expected {
+		if m, ok := arg.(Argument); ok {
+			if !m.Match(actual[i]) {
+				return false
+			}
+			continue
+		}
+		if !reflect.DeepEqual(arg, actual[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,546 @@
+package pgxtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/i4erkasov/go-pgsql/pgx"
+	"github.com/jackc/pgconn"
+	jackc "github.com/jackc/pgx/v4"
+)
+
+// ErrUnexpectedCall is the error returned by Fake for a call that matches no expectation.
+var ErrUnexpectedCall = errors.New("pgxtest: unexpected call")
+
+const (
+	kindBegin    = "Begin"
+	kindCommit   = "Commit"
+	kindRollback = "Rollback"
+	kindExec     = "Exec"
+	kindQuery    = "Query"
+	kindPrepare  = "Prepare"
+	kindCopyFrom = "CopyFrom"
+	// kindStatement is the kind of a statement sent in a batch: it may be read as an Exec or a Query.
+	kindStatement = "Statement"
+)
+
+// QueryMatcher reports why the SQL of a call does not match the SQL of an expectation.
+type QueryMatcher func(expected, actual string) error
+
+// QueryMatcherRegexp matches the SQL of a call against the expected SQL as a regular expression.
+// It is the default QueryMatcher.
+func QueryMatcherRegexp(expected, actual string) error {
+	re, err := regexp.Compile(strings.TrimSpace(expected))
+	if err != nil {
+		return err
+	}
+	if !re.MatchString(actual) {
+		return fmt.Errorf("SQL %q does not match %q", actual, expected)
+	}
+	return nil
+}
+
+// QueryMatcherEqual matches the SQL of a call equal to the expected SQL, ignoring the differences in white space.
+func QueryMatcherEqual(expected, actual string) error {
+	if strings.Join(strings.Fields(expected), " ") != strings.Join(strings.Fields(actual), " ") {
+		return fmt.Errorf("SQL %q is not equal to %q", actual, expected)
+	}
+	return nil
+}
+
+// Argument matches an argument of a call.
+type Argument interface {
+	Match(value any) bool
+}
+
+type anyArg struct{}
+
+func (anyArg) Match(any) bool { return true }
+
+// AnyArg matches any argument.
+func AnyArg() Argument {
+	return anyArg{}
+}
+
+// Fake is a scriptable in-memory pgx.Conn for unit tests. The calls are matched against the expectations
+// registered with its Expect methods, in order unless WithUnordered is set. A call that matches no expectation
+// fails with ErrUnexpectedCall. The transactions it begins are fakes too, matched against the same expectations:
+// Begin on a transaction starts a savepoint and expects ExpectBegin like any other Begin.
+type Fake struct {
+	mu           sync.Mutex
+	matcher      QueryMatcher
+	unordered    bool
+	expectations []*Expectation
+	unexpected   []string
+}
+
+// FakeOption defines the type for functional options for Fake.
+type FakeOption func(*Fake)
+
+// WithQueryMatcher is an option to set how the SQL of the calls is matched (default: QueryMatcherRegexp).
+func WithQueryMatcher(matcher QueryMatcher) FakeOption {
+	return func(f *Fake) {
+		f.matcher = matcher
+	}
+}
+
+// WithUnordered is an option to match the calls against the expectations in any order.
+func WithUnordered() FakeOption {
+	return func(f *Fake) {
+		f.unordered = true
+	}
+}
+
+// NewFake is Fake constructor. The test fails when it ends with unmet expectations or unexpected calls.
+func NewFake(t testing.TB, opts ...FakeOption) *Fake {
+	f := newFake(opts)
+	t.Cleanup(func() {
+		if err := f.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	return f
+}
+
+func newFake(opts []FakeOption) *Fake {
+	f := &Fake{matcher: QueryMatcherRegexp}
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	return f
+}
+
+// ExpectBegin expects a transaction, or a savepoint inside a transaction, to begin.
+func (f *Fake) ExpectBegin() *Expectation {
+	return f.expect(&Expectation{kind: kindBegin})
+}
+
+// ExpectCommit expects a transaction or a savepoint to be committed.
+func (f *Fake) ExpectCommit() *Expectation {
+	return f.expect(&Expectation{kind: kindCommit})
+}
+
+// ExpectRollback expects a transaction or a savepoint to be rolled back.
+func (f *Fake) ExpectRollback() *Expectation {
+	return f.expect(&Expectation{kind: kindRollback})
+}
+
+// ExpectExec expects a statement to be executed with Exec, or sent in a batch.
+func (f *Fake) ExpectExec(sql string) *Expectation {
+	return f.expect(&Expectation{kind: kindExec, sql: sql})
+}
+
+// ExpectQuery expects a query to be run with Query, QueryRow or QueryFunc, or sent in a batch.
+func (f *Fake) ExpectQuery(sql string) *Expectation {
+	return f.expect(&Expectation{kind: kindQuery, sql: sql})
+}
+
+// ExpectPrepare expects a statement to be prepared with the given name.
+func (f *Fake) ExpectPrepare(name, sql string) *Expectation {
+	return f.expect(&Expectation{kind: kindPrepare, name: name, sql: sql})
+}
+
+// ExpectCopyFrom expects rows to be copied into table, written as "schema.table" or "table", with the given columns.
+// It returns the number of rows read from the source, unless WillReturnResult is set.
+func (f *Fake) ExpectCopyFrom(table string, columns ...string) *Expectation {
+	return f.expect(&Expectation{kind: kindCopyFrom, name: table, columns: columns})
+}
+
+func (f *Fake) expect(e *Expectation) *Expectation {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.expectations = append(f.expectations, e)
+	return e
+}
+
+// ExpectationsWereMet returns an error listing the expectations that were not met and the unexpected calls.
+func (f *Fake) ExpectationsWereMet() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var problems []string
+	for _, e := range f.expectations {
+		if !e.met {
+			problems = append(problems, "unmet expectation: "+e.String())
+		}
+	}
+	problems = append(problems, f.unexpected...)
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("pgxtest: %s", strings.Join(problems, "; "))
+}
+
+// call describes a call to the fake.
+type call struct {
+	kind      string
+	sql       string
+	name      string
+	args      []any
+	columns   []string
+	txOptions pgx.TxOptions
+}
+
+func (c call) String() string {
+	switch c.kind {
+	case kindExec, kindQuery, kindStatement, kindPrepare:
+		return fmt.Sprintf("%s %q with args %v", c.kind, c.sql, c.args)
+	case kindCopyFrom:
+		return fmt.Sprintf("%s %s %v", c.kind, c.name, c.columns)
+	default:
+		return c.kind
+	}
+}
+
+// match returns the expectation matching the call, and marks it as met.
+func (f *Fake) match(c call) (*Expectation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	reason := "all the expectations were already met"
+	for _, e := range f.expectations {
+		if e.met {
+			continue
+		}
+
+		err := e.match(c, f.matcher)
+		if err == nil {
+			e.met = true
+			return e, nil
+		}
+		if !f.unordered {
+			reason = fmt.Sprintf("next expectation is %s: %v", e, err)
+			break
+		}
+		reason = "no expectation matches"
+	}
+
+	err := fmt.Errorf("%w: %s, %s", ErrUnexpectedCall, c, reason)
+	f.unexpected = append(f.unexpected, strings.TrimPrefix(err.Error(), "pgxtest: "))
+
+	return nil, err
+}
+
+// Begin begins a fake transaction.
+func (f *Fake) Begin(ctx context.Context) (pgx.Tx, error) {
+	return f.BeginTx(ctx, pgx.TxOptions{})
+}
+
+// BeginTx begins a fake transaction with the given options.
+func (f *Fake) BeginTx(_ context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	e, err := f.match(call{kind: kindBegin, txOptions: txOptions})
+	if err != nil {
+		return nil, err
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	return &fakeTx{fake: f}, nil
+}
+
+// Exec executes a statement.
+func (f *Fake) Exec(_ context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	e, err := f.match(call{kind: kindExec, sql: sql, args: args})
+	if err != nil {
+		return nil, err
+	}
+	return e.tag, e.err
+}
+
+// Query runs a query.
+func (f *Fake) Query(_ context.Context, sql string, args ...interface{}) (jackc.Rows, error) {
+	e, err := f.match(call{kind: kindQuery, sql: sql, args: args})
+	if err != nil {
+		return nil, err
+	}
+	return e.result()
+}
+
+// QueryRow runs a query returning a single row.
+func (f *Fake) QueryRow(ctx context.Context, sql string, args ...interface{}) jackc.Row {
+	rows, err := f.Query(ctx, sql, args...)
+	return &fakeRow{rows: rows, err: err}
+}
+
+// QueryFunc runs a query and calls fn for each row scanned into scans.
+func (f *Fake) QueryFunc(
+	ctx context.Context, sql string, args []interface{}, scans []interface{}, fn func(jackc.QueryFuncRow) error,
+) (pgconn.CommandTag, error) {
+	rows, err := f.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return queryFunc(rows, scans, fn)
+}
+
+// SendBatch is not supported: pgx.Batch does not expose its statements, so they cannot be matched.
+// The call is reported as unexpected and each statement of the batch fails.
+func (f *Fake) SendBatch(_ context.Context, b *jackc.Batch) jackc.BatchResults {
+	err := errors.New("pgxtest: SendBatch is not supported, the statements of a pgx.Batch cannot be read")
+	f.mu.Lock()
+	f.unexpected = append(f.unexpected, strings.TrimPrefix(err.Error(), "pgxtest: "))
+	f.mu.Unlock()
+
+	results := &fakeBatchResults{}
+	for i := 0; i < b.Len(); i++ {
+		results.items = append(results.items, batchResult{err: err})
+	}
+	return results
+}
+
+// SendStatements matches the statements queued in the unit of work of a transaction in order,
+// each against the expectation of an Exec or a Query.
+func (f *Fake) SendStatements(_ context.Context, stmts []pgx.BatchStatement) jackc.BatchResults {
+	results := &fakeBatchResults{}
+	for _, stmt := range stmts {
+		e, err := f.match(call{kind: kindStatement, sql: stmt.SQL, args: stmt.Args})
+		results.items = append(results.items, batchResult{expectation: e, err: err})
+	}
+	return results
+}
+
+// CopyFrom copies the rows of rowSrc.
+func (f *Fake) CopyFrom(_ context.Context, tableName jackc.Identifier, columnNames []string, rowSrc jackc.CopyFromSource) (int64, error) {
+	e, err := f.match(call{kind: kindCopyFrom, name: strings.Join(tableName, "."), columns: columnNames})
+	if err != nil {
+		return 0, err
+	}
+	if e.err != nil {
+		return 0, e.err
+	}
+
+	var n int64
+	for rowSrc.Next() {
+		if _, err = rowSrc.Values(); err != nil {
+			return 0, err
+		}
+		n++
+	}
+	if err = rowSrc.Err(); err != nil {
+		return 0, err
+	}
+	if e.tag != nil {
+		n = e.tag.RowsAffected()
+	}
+	return n, nil
+}
+
+// Prepare prepares a statement.
+func (f *Fake) Prepare(_ context.Context, name, sql string) (*pgconn.StatementDescription, error) {
+	e, err := f.match(call{kind: kindPrepare, name: name, sql: sql})
+	if err != nil {
+		return nil, err
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	return &pgconn.StatementDescription{Name: name, SQL: sql}, nil
+}
+
+// fakeTx is a transaction begun by a Fake.
+type fakeTx struct {
+	fake   *Fake
+	closed bool
+}
+
+func (tx *fakeTx) Begin(ctx context.Context) (jackc.Tx, error) {
+	if tx.closed {
+		return nil, jackc.ErrTxClosed
+	}
+	return tx.fake.Begin(ctx)
+}
+
+func (tx *fakeTx) BeginFunc(ctx context.Context, fn func(jackc.Tx) error) error {
+	sp, err := tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	if err = fn(sp); err != nil {
+		_ = sp.Rollback(ctx)
+		return err
+	}
+	return sp.Commit(ctx)
+}
+
+// Commit commits the transaction. A closed transaction returns pgx.ErrTxClosed without matching an expectation.
+func (tx *fakeTx) Commit(_ context.Context) error {
+	return tx.end(kindCommit)
+}
+
+// Rollback rolls the transaction back. A closed transaction returns pgx.ErrTxClosed without matching
+// an expectation, so that the usual deferred Rollback is not expected after Commit.
+func (tx *fakeTx) Rollback(_ context.Context) error {
+	return tx.end(kindRollback)
+}
+
+func (tx *fakeTx) end(kind string) error {
+	if tx.closed {
+		return jackc.ErrTxClosed
+	}
+	tx.closed = true
+
+	e, err := tx.fake.match(call{kind: kind})
+	if err != nil {
+		return err
+	}
+	return e.err
+}
+
+func (tx *fakeTx) CopyFrom(ctx context.Context, tableName jackc.Identifier, columnNames []string, rowSrc jackc.CopyFromSource) (int64, error) {
+	return tx.fake.CopyFrom(ctx, tableName, columnNames, rowSrc)
+}
+
+func (tx *fakeTx) SendBatch(ctx context.Context, b *jackc.Batch) jackc.BatchResults {
+	return tx.fake.SendBatch(ctx, b)
+}
+
+// SendStatements implements pgx.StatementSender, so that the unit of work does not send a pgx.Batch.
+func (tx *fakeTx) SendStatements(ctx context.Context, stmts []pgx.BatchStatement) jackc.BatchResults {
+	return tx.fake.SendStatements(ctx, stmts)
+}
+
+// LargeObjects is not supported by the fake.
+func (tx *fakeTx) LargeObjects() jackc.LargeObjects {
+	return jackc.LargeObjects{}
+}
+
+func (tx *fakeTx) Prepare(ctx context.Context, name, sql string) (*pgconn.StatementDescription, error) {
+	return tx.fake.Prepare(ctx, name, sql)
+}
+
+func (tx *fakeTx) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return tx.fake.Exec(ctx, sql, args...)
+}
+
+func (tx *fakeTx) Query(ctx context.Context, sql string, args ...interface{}) (jackc.Rows, error) {
+	return tx.fake.Query(ctx, sql, args...)
+}
+
+func (tx *fakeTx) QueryRow(ctx context.Context, sql string, args ...interface{}) jackc.Row {
+	return tx.fake.QueryRow(ctx, sql, args...)
+}
+
+func (tx *fakeTx) QueryFunc(
+	ctx context.Context, sql string, args []interface{}, scans []interface{}, fn func(jackc.QueryFuncRow) error,
+) (pgconn.CommandTag, error) {
+	return tx.fake.QueryFunc(ctx, sql, args, scans, fn)
+}
+
+// Conn returns nil: there is no underlying connection.
+func (tx *fakeTx) Conn() *jackc.Conn {
+	return nil
+}
+
+// batchResult is the result of a statement sent in a batch.
+type batchResult struct {
+	expectation *Expectation
+	err         error
+}
+
+// fakeBatchResults returns the results of the statements of a batch in order.
+type fakeBatchResults struct {
+	items []batchResult
+	next  int
+}
+
+func (r *fakeBatchResults) result() (*Expectation, error) {
+	if r.next >= len(r.items) {
+		return nil, errors.New("pgxtest: no more results in the batch")
+	}
+	item := r.items[r.next]
+	r.next++
+	return item.expectation, item.err
+}
+
+func (r *fakeBatchResults) Exec() (pgconn.CommandTag, error) {
+	e, err := r.result()
+	if err != nil {
+		return nil, err
+	}
+	return e.tag, e.err
+}
+
+func (r *fakeBatchResults) Query() (jackc.Rows, error) {
+	e, err := r.result()
+	if err != nil {
+		return nil, err
+	}
+	return e.result()
+}
+
+func (r *fakeBatchResults) QueryRow() jackc.Row {
+	rows, err := r.Query()
+	return &fakeRow{rows: rows, err: err}
+}
+
+func (r *fakeBatchResults) QueryFunc(scans []interface{}, fn func(jackc.QueryFuncRow) error) (pgconn.CommandTag, error) {
+	rows, err := r.Query()
+	if err != nil {
+		return nil, err
+	}
+	return queryFunc(rows, scans, fn)
+}
+
+// Close returns the first error of the results that were not read.
+func (r *fakeBatchResults) Close() error {
+	for r.next < len(r.items) {
+		e, err := r.result()
+		if err == nil {
+			err = e.err
+		}
+		if err != nil {
+			r.next = len(r.items)
+			return err
+		}
+	}
+	return nil
+}
+
+// fakeRow is the row returned by QueryRow.
+type fakeRow struct {
+	rows jackc.Rows
+	err  error
+}
+
+// Scan scans the first row, or returns pgx.ErrNoRows when there is none.
+func (r *fakeRow) Scan(dest ...interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	defer r.rows.Close()
+
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return jackc.ErrNoRows
+	}
+	return r.rows.Scan(dest...)
+}
+
+// queryFunc scans the rows into scans and calls fn for each of them.
+func queryFunc(rows jackc.Rows, scans []interface{}, fn func(jackc.QueryFuncRow) error) (pgconn.CommandTag, error) {
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := rows.Scan(scans...); err != nil {
+			return nil, err
+		}
+		if err := fn(rows); err != nil {
+			return nil, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return rows.CommandTag(), nil
+}
@@ -0,0 +1,152 @@
+package pgxtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/i4erkasov/go-pgsql/pgx"
+	"github.com/jackc/pgconn"
+	jackc "github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/suite"
+)
+
+// FakeTestSuite defines the structure for the test suite.
+type FakeTestSuite struct {
+	suite.Suite
+}
+
+// TestWithTx checks a transaction run by a TxManager on the fake.
+func (t *FakeTestSuite) TestWithTx() {
+	fake := NewFake(t.T())
+	fake.ExpectBegin()
+	fake.ExpectExec("INSERT INTO users").WithArgs("John Doe", AnyArg()).
+		WillReturnResult(pgconn.CommandTag("INSERT 0 1"))
+	fake.ExpectQuery(`SELECT id, name, email FROM users WHERE id = \$1`).WithArgs(1).
+		WillReturnRows(NewRows("id", "name", "email").AddRow(int32(1), "John Doe", nil))
+	fake.ExpectCommit()
+
+	txManager := pgx.NewTxManagerFromConn(fake)
+
+	var (
+		id    int64
+		name  string
+		email *string
+	)
+	err := txManager.WithTx(context.Background(), func(ctx context.Context, tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, "INSERT INTO users (name, age) VALUES ($1, $2)", "John Doe", 42)
+		if err != nil {
+			return err
+		}
+		t.Equal(int64(1), tag.RowsAffected())
+
+		return txManager.Querier(ctx).QueryRow(ctx, "SELECT id, name, email FROM users WHERE id = $1", 1).
+			Scan(&id, &name, &email)
+	})
+
+	t.NoError(err)
+	t.Equal(int64(1), id)
+	t.Equal("John Doe", name)
+	t.Nil(email)
+}
+
+// TestRollback checks that the error of the function rolls the transaction back.
+func (t *FakeTestSuite) TestRollback() {
+	fake := NewFake(t.T())
+	fake.ExpectBegin()
+	fake.ExpectQuery("SELECT name FROM users").WillReturnRows(NewRows("name"))
+	fake.ExpectRollback()
+
+	err := pgx.NewTxManagerFromConn(fake).WithTx(context.Background(), func(ctx context.Context, tx pgx.Tx) error {
+		var name string
+		return tx.QueryRow(ctx, "SELECT name FROM users").Scan(&name)
+	})
+
+	t.ErrorIs(err, jackc.ErrNoRows)
+}
+
+// TestUnitOfWork checks that the statements of a batch are matched in order.
+func (t *FakeTestSuite) TestUnitOfWork() {
+	fake := NewFake(t.T(), WithQueryMatcher(QueryMatcherEqual))
+	fake.ExpectBegin()
+	fake.ExpectExec("INSERT INTO users (name) VALUES ($1)").WithArgs("John Doe")
+	fake.ExpectExec("INSERT INTO users (name) VALUES ($1)").WithArgs("Jane Doe")
+	fake.ExpectCommit()
+
+	err := pgx.NewTxManagerFromConn(fake).WithTx(context.Background(), func(ctx context.Context, tx pgx.Tx) error {
+		if err := pgx.Queue(ctx, "INSERT INTO users (name) VALUES ($1)", "John Doe"); err != nil {
+			return err
+		}
+		return pgx.Queue(ctx, "INSERT INTO users (name) VALUES ($1)", "Jane Doe")
+	})
+
+	t.NoError(err)
+}
+
+// TestSendBatch checks that SendBatch is reported, as the statements of a pgx.Batch cannot be read.
+func (t *FakeTestSuite) TestSendBatch() {
+	fake := newFake(nil)
+	batch := &jackc.Batch{}
+	batch.Queue("DELETE FROM users")
+
+	results := fake.SendBatch(context.Background(), batch)
+	_, err := results.Exec()
+
+	t.ErrorContains(err, "SendBatch is not supported")
+	t.ErrorContains(fake.ExpectationsWereMet(), "SendBatch is not supported")
+}
+
+// TestUnexpectedCall checks that the calls out of order are reported with the unmet expectations.
+func (t *FakeTestSuite) TestUnexpectedCall() {
+	fake := newFake(nil)
+	fake.ExpectBegin()
+	fake.ExpectCommit()
+
+	_, err := fake.Exec(context.Background(), "DELETE FROM users")
+
+	t.ErrorIs(err, ErrUnexpectedCall)
+	t.ErrorContains(err, `next expectation is Begin`)
+
+	err = fake.ExpectationsWereMet()
+	t.ErrorContains(err, "unmet expectation: Begin; unmet expectation: Commit; unexpected call: Exec")
+}
+
+// TestUnordered checks that the calls match any unmet expectation with WithUnordered.
+func (t *FakeTestSuite) TestUnordered() {
+	fake := NewFake(t.T(), WithUnordered())
+	fake.ExpectExec("UPDATE users").WillReturnError(errors.New("deadlock"))
+	fake.ExpectExec("DELETE FROM users")
+
+	_, err := fake.Exec(context.Background(), "DELETE FROM users")
+	t.NoError(err)
+
+	_, err = fake.Exec(context.Background(), "UPDATE users SET name = $1", "John Doe")
+	t.EqualError(err, "deadlock")
+}
+
+// TestScan checks the conversions of the canned values.
+func (t *FakeTestSuite) TestScan() {
+	rows := NewRows("id", "name", "score", "skipped").AddRow(int64(7), "Jane", 1.5, "x").RowError(1, errors.New("broken"))
+
+	var (
+		id    int32
+		name  *string
+		score float32
+	)
+	t.True(rows.Next())
+	t.NoError(rows.Scan(&id, &name, &score, nil))
+	t.Equal(int32(7), id)
+	t.Equal("Jane", *name)
+	t.Equal(float32(1.5), score)
+
+	var wrong int
+	t.Error(rows.Scan(&wrong, &wrong, &wrong, nil))
+
+	t.False(rows.Next())
+	t.EqualError(rows.Err(), "broken")
+}
+
+// TestFakeSuite runs the test suite.
+func TestFakeSuite(t *testing.T) {
+	suite.Run(t, new(FakeTestSuite))
+}
@@ -0,0 +1,113 @@
+// Package pgxtest provides databases for the integration tests that do not leak data between tests.
+//
+// New runs the transactions of each test inside a single transaction rolled back when the test ends.
+// NewClone gives each test its own database, cloned from a template database migrated once.
+package pgxtest
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/i4erkasov/go-pgsql/pgx"
+	"github.com/i4erkasov/go-pgsql/pgxpool"
+)
+
+// DSNEnv is the environment variable holding the DSN of the test database server.
+const DSNEnv = "PGSQL_TEST_DSN"
+
+// DB is the database of a test.
+type DB struct {
+	// Registry holds the DEFAULT pool of the test database. With New, the queries run
+	// directly on its pools are not part of the test transaction.
+	Registry  *pgxpool.Registry
+	TxManager pgx.TxManager
+}
+
+// DSN returns the DSN of the test database from the PGSQL_TEST_DSN environment variable.
+// The test is skipped when it is not set.
+func DSN(t testing.TB) string {
+	t.Helper()
+
+	dsn := os.Getenv(DSNEnv)
+	if dsn == "" {
+		t.Skipf("pgxtest: %s is not set", DSNEnv)
+	}
+
+	return dsn
+}
+
+// New returns the test database with a TxManager running everything inside a single transaction,
+// rolled back when the test ends. WithTx and WithNestedTx start savepoints of that transaction,
+// and the queries outside of them run on it too. As a transaction holds a single connection,
+// the TxManager must not be used concurrently, and the transaction options are ignored.
+func New(t testing.TB, opts ...pgx.Option) *DB {
+	t.Helper()
+
+	registry := newRegistry(t, DSN(t))
+	pools, err := registry.Pools()
+	if err != nil {
+		t.Fatalf("pgxtest: %v", err)
+	}
+
+	tx, err := pools.Master().Begin(context.Background())
+	if err != nil {
+		t.Fatalf("pgxtest: failed to begin the test transaction: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := tx.Rollback(context.Background()); err != nil {
+			t.Errorf("pgxtest: failed to roll back the test transaction: %v", err)
+		}
+	})
+
+	return &DB{
+		Registry:  registry,
+		TxManager: pgx.NewTxManagerFromConn(&txConn{Tx: tx}, opts...),
+	}
+}
+
+// txConn is a Conn beginning savepoints of the test transaction.
+type txConn struct {
+	pgx.Tx
+}
+
+// BeginTx starts a savepoint: the options can't be changed inside a transaction.
+func (c *txConn) BeginTx(ctx context.Context, _ pgx.TxOptions) (pgx.Tx, error) {
+	return c.Tx.Begin(ctx)
+}
+
+// newRegistry creates a registry with the DEFAULT pool on dsn, closed when the test ends.
+func newRegistry(t testing.TB, dsn string) *pgxpool.Registry {
+	t.Helper()
+
+	registry, err := pgxpool.NewWithConfigOptions(
+		pgxpool.WithConfig(pgxpool.DEFAULT, pgxpool.Config{Nodes: []string{dsn}}),
+	)
+	if err != nil {
+		t.Fatalf("pgxtest: failed to connect to the test database: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = registry.Close()
+	})
+
+	return registry
+}
+
+// withDatabase returns dsn with its database replaced by name. dsn is either a URL
+// or a list of key/value pairs.
+func withDatabase(dsn, name string) (string, error) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", err
+		}
+		u.Path = "/" + name
+		u.RawPath = ""
+		return u.String(), nil
+	}
+
+	// The last value of a key wins.
+	return dsn + " dbname=" + name, nil
+}
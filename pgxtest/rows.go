@@ -0,0 +1,158 @@
+package pgxtest
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgproto3/v2"
+)
+
+// Rows are the canned rows returned by a query of a Fake.
+type Rows struct {
+	columns []string
+	values  [][]any
+	errs    map[int]error
+	row     int
+	err     error
+	closed  bool
+}
+
+// NewRows creates rows with the given columns.
+func NewRows(columns ...string) *Rows {
+	return &Rows{columns: columns, row: -1}
+}
+
+// AddRow adds a row with a value for each column.
+func (r *Rows) AddRow(values ...any) *Rows {
+	r.values = append(r.values, values)
+	return r
+}
+
+// RowError makes the iteration fail with err when it reaches the row with the given index.
+func (r *Rows) RowError(row int, err error) *Rows {
+	if r.errs == nil {
+		r.errs = make(map[int]error)
+	}
+	r.errs[row] = err
+	return r
+}
+
+func (r *Rows) Close() {
+	r.closed = true
+}
+
+func (r *Rows) Err() error {
+	return r.err
+}
+
+func (r *Rows) CommandTag() pgconn.CommandTag {
+	return pgconn.CommandTag(fmt.Sprintf("SELECT %d", len(r.values)))
+}
+
+func (r *Rows) FieldDescriptions() []pgproto3.FieldDescription {
+	fields := make([]pgproto3.FieldDescription, len(r.columns))
+	for i, column := range r.columns {
+		fields[i].Name = []byte(column)
+	}
+	return fields
+}
+
+func (r *Rows) Next() bool {
+	if r.closed {
+		return false
+	}
+
+	r.row++
+	if err, ok := r.errs[r.row]; ok {
+		r.err = err
+		r.Close()
+		return false
+	}
+	if r.row >= len(r.values) {
+		r.Close()
+		return false
+	}
+	return true
+}
+
+// Scan assigns the values of the current row to dest. A value is assigned when it is assignable
+// or convertible between numbers or strings, and a nil value sets the zero value.
+// A nil destination skips its value, and a destination implementing sql.Scanner scans it.
+func (r *Rows) Scan(dest ...interface{}) error {
+	values := r.values[r.row]
+	if len(dest) != len(values) {
+		return fmt.Errorf("pgxtest: %d destinations for %d values", len(dest), len(values))
+	}
+
+	for i, d := range dest {
+		if err := assign(d, values[i]); err != nil {
+			return fmt.Errorf("pgxtest: can't scan column %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (r *Rows) Values() ([]interface{}, error) {
+	return append([]any(nil), r.values[r.row]...), nil
+}
+
+// RawValues returns nil: the canned values are not encoded.
+func (r *Rows) RawValues() [][]byte {
+	return nil
+}
+
+func assign(dest, value any) error {
+	if dest == nil {
+		return nil
+	}
+	if scanner, ok := dest.(sql.Scanner); ok {
+		return scanner.Scan(value)
+	}
+
+	d := reflect.ValueOf(dest)
+	if d.Kind() != reflect.Pointer || d.IsNil() {
+		return fmt.Errorf("destination %T is not a non-nil pointer", dest)
+	}
+	d = d.Elem()
+
+	if value == nil {
+		d.Set(reflect.Zero(d.Type()))
+		return nil
+	}
+
+	v := reflect.ValueOf(value)
+	switch {
+	case v.Type().AssignableTo(d.Type()):
+		d.Set(v)
+	case d.Kind() == reflect.Pointer && v.Type().AssignableTo(d.Type().Elem()):
+		p := reflect.New(d.Type().Elem())
+		p.Elem().Set(v)
+		d.Set(p)
+	case convertible(v.Type(), d.Type()):
+		d.Set(v.Convert(d.Type()))
+	default:
+		return fmt.Errorf("%T is not assignable to %s", value, d.Type())
+	}
+	return nil
+}
+
+// convertible reports whether a value converts between the types without changing its meaning,
+// unlike an integer converted to a string.
+func convertible(from, to reflect.Type) bool {
+	switch {
+	case isNumber(from.Kind()) && isNumber(to.Kind()):
+		return true
+	case from.Kind() == reflect.String && to.Kind() == reflect.String:
+		return true
+	case from.Kind() == reflect.String && to == reflect.TypeOf([]byte(nil)),
+		from == reflect.TypeOf([]byte(nil)) && to.Kind() == reflect.String:
+		return true
+	}
+	return false
+}
+
+func isNumber(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Float64
+}
@@ -0,0 +1,197 @@
+package pgxtest
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/i4erkasov/go-pgsql/migrate"
+	"github.com/i4erkasov/go-pgsql/pgx"
+	jackc "github.com/jackc/pgx/v4"
+)
+
+const templatePrefix = "pgxtest_"
+
+// Template is a database migrated once and cloned for each test by NewClone.
+// Declare it as a package variable shared by the tests.
+type Template struct {
+	// Migrations configures the migrations applied to the template.
+	// DataSourceName and DatabaseName are set by NewClone.
+	Migrations migrate.Config
+
+	once sync.Once
+	name string
+	err  error
+}
+
+// NewClone returns a fresh test database, created from the template and dropped when the test ends.
+// The template is created on the first call: its name is derived from the content of the migrations,
+// so that it is reused by later test runs, and created again when the migrations change.
+func NewClone(t testing.TB, template *Template, opts ...pgx.Option) *DB {
+	t.Helper()
+
+	dsn := DSN(t)
+	ctx := context.Background()
+
+	template.once.Do(func() {
+		template.name, template.err = template.create(ctx, dsn)
+	})
+	if template.err != nil {
+		t.Fatalf("pgxtest: failed to create the template database: %v", template.err)
+	}
+
+	name := template.name + "_" + randomHex(4)
+	err := withAdmin(ctx, dsn, template.name, func(conn *jackc.Conn) error {
+		_, err := conn.Exec(ctx, fmt.Sprintf("CREATE DATABASE %s TEMPLATE %s", quote(name), quote(template.name)))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("pgxtest: failed to clone the template database: %v", err)
+	}
+	// Registered before the registry, so that the database is dropped once its pool is closed.
+	t.Cleanup(func() {
+		err := withAdmin(ctx, dsn, template.name, func(conn *jackc.Conn) error {
+			_, err := conn.Exec(ctx, "DROP DATABASE IF EXISTS "+quote(name))
+			return err
+		})
+		if err != nil {
+			t.Errorf("pgxtest: failed to drop the test database: %v", err)
+		}
+	})
+
+	cloneDSN, err := withDatabase(dsn, name)
+	if err != nil {
+		t.Fatalf("pgxtest: %v", err)
+	}
+	registry := newRegistry(t, cloneDSN)
+
+	txManager, err := pgx.NewTxManager(registry, opts...)
+	if err != nil {
+		t.Fatalf("pgxtest: %v", err)
+	}
+
+	return &DB{
+		Registry:  registry,
+		TxManager: txManager,
+	}
+}
+
+// create creates the template database unless it exists, and returns its name. It is built under
+// a temporary name and renamed once migrated, so that a failed migration never leaves a template behind.
+func (tpl *Template) create(ctx context.Context, dsn string) (string, error) {
+	config := tpl.Migrations
+	migrate.SetDefaultConfigValues(&config)
+
+	sum, err := migrationsSum(config)
+	if err != nil {
+		return "", err
+	}
+	name := templatePrefix + sum
+
+	err = withAdmin(ctx, dsn, name, func(conn *jackc.Conn) error {
+		var exists bool
+		err := conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)", name).Scan(&exists)
+		if err != nil || exists {
+			return err
+		}
+
+		build := name + "_build"
+		if _, err = conn.Exec(ctx, "DROP DATABASE IF EXISTS "+quote(build)); err != nil {
+			return err
+		}
+		if _, err = conn.Exec(ctx, "CREATE DATABASE "+quote(build)); err != nil {
+			return err
+		}
+
+		if config.DataSourceName, err = withDatabase(dsn, build); err != nil {
+			return err
+		}
+		config.DatabaseName = build
+		m, err := migrate.NewWithConfig(config)
+		if err != nil {
+			return err
+		}
+		if err = m.Up(ctx); err != nil {
+			return err
+		}
+
+		_, err = conn.Exec(ctx, fmt.Sprintf("ALTER DATABASE %s RENAME TO %s", quote(build), quote(name)))
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return name, nil
+}
+
+// withAdmin runs fn on a connection to dsn holding an advisory lock on the template name,
+// so that the test processes running in parallel do not create or clone it at the same time.
+func withAdmin(ctx context.Context, dsn, template string, fn func(*jackc.Conn) error) error {
+	conn, err := jackc.Connect(ctx, dsn)
+	if err != nil {
+		return err
+	}
+	// Closing the connection releases the lock.
+	defer conn.Close(context.Background())
+
+	if _, err = conn.Exec(ctx, "SELECT pg_advisory_lock(hashtext($1))", template); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+// migrationsSum returns a hash of the names and contents of the migration files.
+func migrationsSum(config migrate.Config) (string, error) {
+	var (
+		src fs.FS = os.DirFS(config.PathMigration)
+		err error
+	)
+	if config.FS != nil {
+		if src, err = fs.Sub(config.FS, config.PathMigration); err != nil {
+			return "", err
+		}
+	}
+
+	h := sha256.New()
+	err = fs.WalkDir(src, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		f, err := src.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, _ = fmt.Fprintf(h, "%s\x00", path)
+		_, err = io.Copy(h, f)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to read the migrations: %w", err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:16], nil
+}
+
+// quote quotes a database name.
+func quote(name string) string {
+	return jackc.Identifier{name}.Sanitize()
+}
+
+// randomHex returns n random bytes hex encoded.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,197 @@
+// Package sqlfile loads named SQL queries from .sql files, such as the files of an embed.FS.
+//
+// Each query of a file is introduced by a "-- name: <Name>" comment and runs until the next one:
+//
+//	-- name: GetUser
+//	SELECT id, name FROM users WHERE id = $1;
+//
+//	-- name: DeleteUser
+//	DELETE FROM users WHERE id = $1;
+package sqlfile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/i4erkasov/go-pgsql/pgxpool"
+)
+
+const defaultPattern = "*.sql"
+
+var (
+	// ErrUnknownQuery is the error returned by Get when no query has the name.
+	ErrUnknownQuery = errors.New("unknown query")
+
+	nameMarker = regexp.MustCompile(`^\s*--\s*name:\s*(\S+)\s*$`)
+)
+
+// Query is a named query read from a file.
+type Query struct {
+	Name string
+	SQL  string
+	// File is the path of the file in the fs.FS.
+	File string
+}
+
+// Queries holds the queries loaded by name.
+type Queries struct {
+	queries map[string]Query
+}
+
+// Load reads the queries of the files of fsys matching the patterns, "*.sql" if there is none.
+// The names of the queries must be unique across the files.
+func Load(fsys fs.FS, patterns ...string) (*Queries, error) {
+	if len(patterns) == 0 {
+		patterns = []string{defaultPattern}
+	}
+
+	q := &Queries{queries: make(map[string]Query)}
+	for _, pattern := range patterns {
+		files, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			data, err := fs.ReadFile(fsys, file)
+			if err != nil {
+				return nil, err
+			}
+
+			queries, err := parse(file, string(data))
+			if err != nil {
+				return nil, err
+			}
+			for _, query := range queries {
+				if other, ok := q.queries[query.Name]; ok {
+					return nil, fmt.Errorf("query %s is defined in %s and %s", query.Name, other.File, query.File)
+				}
+				q.queries[query.Name] = query
+			}
+		}
+	}
+
+	return q, nil
+}
+
+// parse returns the queries of a file.
+func parse(file, data string) ([]Query, error) {
+	var (
+		queries []Query
+		body    []string
+	)
+
+	flush := func() error {
+		if len(queries) == 0 {
+			return nil
+		}
+		last := &queries[len(queries)-1]
+		last.SQL = strings.TrimSuffix(strings.TrimSpace(strings.Join(body, "\n")), ";")
+		if last.SQL == "" {
+			return fmt.Errorf("query %s in %s is empty", last.Name, file)
+		}
+		body = nil
+		return nil
+	}
+
+	for i, line := range strings.Split(data, "\n") {
+		if m := nameMarker.FindStringSubmatch(line); m != nil {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			queries = append(queries, Query{Name: m[1], File: file})
+			continue
+		}
+
+		if len(queries) == 0 {
+			if trimmed := strings.TrimSpace(line); trimmed != "" && !strings.HasPrefix(trimmed, "--") {
+				return nil, fmt.Errorf("%s:%d: SQL before the first \"-- name:\" comment", file, i+1)
+			}
+			continue
+		}
+		body = append(body, line)
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return queries, nil
+}
+
+// Get returns the SQL of the query with the given name.
+func (q *Queries) Get(name string) (string, error) {
+	query, ok := q.queries[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownQuery, name)
+	}
+	return query.SQL, nil
+}
+
+// MustGet returns the SQL of the query with the given name, and panics when there is none.
+func (q *Queries) MustGet(name string) string {
+	sql, err := q.Get(name)
+	if err != nil {
+		panic(err)
+	}
+	return sql
+}
+
+// Query returns the query with the given name.
+func (q *Queries) Query(name string) (Query, bool) {
+	query, ok := q.queries[name]
+	return query, ok
+}
+
+// Names returns the names of the queries, sorted.
+func (q *Queries) Names() []string {
+	names := make([]string, 0, len(q.queries))
+	for name := range q.queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Statements returns the SQL of the queries by name, to be prepared on every connection
+// of a pool with pgxpool.WithPreparedStatements.
+func (q *Queries) Statements() map[string]string {
+	statements := make(map[string]string, len(q.queries))
+	for name, query := range q.queries {
+		statements[name] = query.SQL
+	}
+
+	return statements
+}
+
+// Validate prepares every query on a connection of the master node of the pool with the given name
+// of the registry, and returns the errors of the queries that fail, so that they are caught at startup.
+func (q *Queries) Validate(ctx context.Context, registry *pgxpool.Registry, name string) error {
+	pools, err := registry.GetPoolName(name)
+	if err != nil {
+		return err
+	}
+
+	conn, err := pools.Master().Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	var errs []error
+	for _, queryName := range q.Names() {
+		query := q.queries[queryName]
+		// The unnamed statement is replaced by the next one, so nothing is left behind.
+		if _, err = conn.Conn().Prepare(ctx, "", query.SQL); err != nil {
+			errs = append(errs, fmt.Errorf("query %s in %s: %w", query.Name, query.File, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
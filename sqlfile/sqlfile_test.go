@@ -0,0 +1,99 @@
+package sqlfile
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/i4erkasov/go-pgsql/pgxpool"
+	"github.com/stretchr/testify/suite"
+)
+
+// SQLFileTestSuite defines the structure for the test suite.
+type SQLFileTestSuite struct {
+	suite.Suite
+}
+
+// TestLoad checks that the queries are split on the name comments.
+func (t *SQLFileTestSuite) TestLoad() {
+	fsys := fstest.MapFS{
+		"users.sql": {Data: []byte(`-- Queries of the users.
+
+-- name: GetUser
+-- Returns a user by ID.
+SELECT id, name
+FROM users
+WHERE id = $1;
+
+--name:DeleteUser
+DELETE FROM users WHERE id = $1;
+`)},
+		"orders.sql": {Data: []byte("-- name: ListOrders\nSELECT id FROM orders\n")},
+		"README.md":  {Data: []byte("not SQL")},
+	}
+
+	queries, err := Load(fsys)
+
+	t.Require().NoError(err)
+	t.Equal([]string{"DeleteUser", "GetUser", "ListOrders"}, queries.Names())
+	t.Equal("-- Returns a user by ID.\nSELECT id, name\nFROM users\nWHERE id = $1", queries.MustGet("GetUser"))
+	t.Equal("DELETE FROM users WHERE id = $1", queries.MustGet("DeleteUser"))
+
+	query, ok := queries.Query("ListOrders")
+	t.True(ok)
+	t.Equal("orders.sql", query.File)
+	t.Equal(map[string]string{
+		"GetUser":    queries.MustGet("GetUser"),
+		"DeleteUser": "DELETE FROM users WHERE id = $1",
+		"ListOrders": "SELECT id FROM orders",
+	}, queries.Statements())
+
+	_, err = queries.Get("UpdateUser")
+	t.ErrorIs(err, ErrUnknownQuery)
+	t.Panics(func() { queries.MustGet("UpdateUser") })
+}
+
+// TestLoadErrors checks the invalid files.
+func (t *SQLFileTestSuite) TestLoadErrors() {
+	tests := map[string]struct {
+		fsys fstest.MapFS
+		err  string
+	}{
+		"duplicate": {
+			fsys: fstest.MapFS{
+				"a.sql": {Data: []byte("-- name: GetUser\nSELECT 1")},
+				"b.sql": {Data: []byte("-- name: GetUser\nSELECT 2")},
+			},
+			err: "query GetUser is defined in a.sql and b.sql",
+		},
+		"empty": {
+			fsys: fstest.MapFS{"a.sql": {Data: []byte("-- name: GetUser\n\n-- name: DeleteUser\nSELECT 1")}},
+			err:  "query GetUser in a.sql is empty",
+		},
+		"unnamed": {
+			fsys: fstest.MapFS{"a.sql": {Data: []byte("-- Users\nSELECT 1\n-- name: GetUser\nSELECT 2")}},
+			err:  `a.sql:2: SQL before the first "-- name:" comment`,
+		},
+	}
+
+	for name, test := range tests {
+		_, err := Load(test.fsys)
+		t.EqualError(err, test.err, name)
+	}
+}
+
+// TestValidateUnknownPool checks that the pool must be registered.
+func (t *SQLFileTestSuite) TestValidateUnknownPool() {
+	registry, err := pgxpool.NewRegistry(pgxpool.Configs{})
+	t.Require().NoError(err)
+
+	queries, err := Load(fstest.MapFS{})
+	t.Require().NoError(err)
+
+	t.ErrorIs(queries.Validate(context.Background(), registry, "reports"), pgxpool.ErrUnknownPool)
+}
+
+// TestSQLFileSuite runs the test suite.
+func TestSQLFileSuite(t *testing.T) {
+	suite.Run(t, new(SQLFileTestSuite))
+}